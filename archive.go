@@ -0,0 +1,129 @@
+package fixturer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// WithFixturesArchive loads the .yml, .json and .csv entries of the .tar.gz, .tgz, .tar or .zip archive instead of the fixtures directory.
+// The tables are named after the entry base names. The entries are read into memory, so they are never streamed.
+// !include is not supported in the archive entries.
+func (this *Fixturer) WithFixturesArchive(path string) IFixturer {
+	this.fixturesArchive = path
+	return this
+}
+
+// archiveFiles returns the fixture entries of the archive sorted by name.
+func archiveFiles(archive string) ([]fixtureFile, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return readArchive(archive, file, info.Size())
+}
+
+// readArchive returns the fixture entries of the archive content sorted by name, the archive name
+// tells its format.
+func readArchive(archive string, reader io.ReaderAt, size int64) ([]fixtureFile, error) {
+	var files []fixtureFile
+	var err error
+	switch {
+	case strings.HasSuffix(archive, ".zip"):
+		files, err = zipFiles(archive, reader, size)
+	case isTarArchive(archive):
+		files, err = tarFiles(archive, io.NewSectionReader(reader, 0, size))
+	default:
+		return nil, fmt.Errorf("unsupported fixtures archive %s, expected .tar.gz, .tgz, .tar or .zip", archive)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", archive, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// isArchive reports whether the name has the extension of a supported archive.
+func isArchive(name string) bool {
+	return strings.HasSuffix(name, ".zip") || isTarArchive(name)
+}
+
+func isTarArchive(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") || strings.HasSuffix(name, ".tar")
+}
+
+func tarFiles(archive string, reader io.Reader) ([]fixtureFile, error) {
+	if !strings.HasSuffix(archive, ".tar") {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var files []fixtureFile
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || fixtureExt(header.Name) == "" {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archiveFixtureFile(archive, header.Name, header.FileInfo(), data))
+	}
+}
+
+func zipFiles(archive string, reader io.ReaderAt, size int64) ([]fixtureFile, error) {
+	zr, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []fixtureFile
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() || fixtureExt(entry.Name) == "" {
+			continue
+		}
+		reader, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archiveFixtureFile(archive, entry.Name, entry.FileInfo(), data))
+	}
+	return files, nil
+}
+
+// archiveFixtureFile makes the fixture of the archive entry, its path is the entry path inside the archive path,
+// e.g. fixtures.tar.gz/users.yml, so the errors point to the entry.
+func archiveFixtureFile(archive, name string, info os.FileInfo, data []byte) fixtureFile {
+	return fixtureFile{FileInfo: info, path: archive + "/" + strings.TrimPrefix(path.Clean(name), "/"), data: data}
+}
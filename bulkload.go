@@ -0,0 +1,164 @@
+package fixturer
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL errors returned when the server refuses LOAD DATA LOCAL INFILE.
+const (
+	mysqlErrNotAllowedCommand       = 1148
+	mysqlErrClientLocalFileDisabled = 3948
+)
+
+var bulkLoadEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x00", `\0`,
+)
+
+// bulkLoad streams the rows to LOAD DATA LOCAL INFILE inside the worker transaction, encoding them as TSV
+// while the driver sends them, so neither a temporary file nor the whole encoded table is needed.
+func (this *insertWorker) bulkLoad(table string, columns []string, rows []map[string]interface{}) error {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := "fixturer-" + hex.EncodeToString(suffix)
+
+	binary := binaryColumns(columns, rows)
+	reader, writer := io.Pipe()
+	// Closing the reader stops the writer if the server does not read everything, e.g. on an error.
+	defer reader.Close()
+	go func() {
+		writer.CloseWithError(writeBulkLoadRows(writer, columns, binary, rows))
+	}()
+	mysql.RegisterReaderHandler(name, func() io.Reader { return reader })
+	defer mysql.DeregisterReaderHandler(name)
+
+	_, err := this.tx.Exec(bulkLoadStatement(name, table, columns, binary))
+	return err
+}
+
+// bulkLoadStatement returns the LOAD DATA statement of the reader. The file is read as utf8mb4, so the values
+// of the binary columns are loaded hex encoded into the user variables and decoded with UNHEX, keeping
+// the bytes which are not valid UTF-8 intact.
+func bulkLoadStatement(name, table string, columns []string, binary map[string]struct{}) string {
+	targets := make([]string, len(columns))
+	var set []string
+	for i, column := range columns {
+		targets[i] = QuoteMySQLIdentifier(column)
+		if _, find := binary[column]; find {
+			variable := fmt.Sprintf("@fixturer_%d", i)
+			set = append(set, targets[i]+" = UNHEX("+variable+")")
+			targets[i] = variable
+		}
+	}
+
+	statement := fmt.Sprintf(
+		"LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 "+
+			`FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n' (%s)`,
+		name,
+		quoteQualifiedName(QuoteMySQLIdentifier, table),
+		strings.Join(targets, ", "),
+	)
+	if len(set) > 0 {
+		statement += " SET " + strings.Join(set, ", ")
+	}
+	return statement
+}
+
+// binaryColumns returns the columns with a []byte value in any row, e.g. of the !base64 and !hex values.
+func binaryColumns(columns []string, rows []map[string]interface{}) map[string]struct{} {
+	binary := map[string]struct{}{}
+	for _, column := range columns {
+		for _, row := range rows {
+			if _, ok := row[column].([]byte); ok {
+				binary[column] = struct{}{}
+				break
+			}
+		}
+	}
+	return binary
+}
+
+// bulkLoads reports whether the rows are loaded with LOAD DATA LOCAL INFILE, which can't insert DEFAULT
+// or update the existing rows, and inserts NULL for the omitted columns.
+func (this *Fixturer) bulkLoads(table string, columns []string, rows []map[string]interface{}) bool {
+	return this.bulkLoad && this.dialect.LocalInfile() && !this.upserts(table) &&
+		(this.missingColumns == MissingColumnsNull || !sparseRows(rows, columns)) && !hasDefaults(rows)
+}
+
+func writeBulkLoadRows(writer io.Writer, columns []string, binary map[string]struct{}, rows []map[string]interface{}) error {
+	w := bufio.NewWriter(writer)
+	for _, row := range rows {
+		for i, column := range columns {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			if _, find := binary[column]; find {
+				w.WriteString(bulkLoadHexValue(row[column]))
+			} else {
+				w.WriteString(bulkLoadValue(row[column]))
+			}
+		}
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// bulkLoadValue encodes a fixture value as a LOAD DATA field. A missing or nil value is encoded as NULL.
+func bulkLoadValue(value interface{}) string {
+	if value == nil {
+		return `\N`
+	}
+	return bulkLoadEscaper.Replace(bulkLoadText(value))
+}
+
+// bulkLoadHexValue encodes a fixture value of a binary column as the hex LOAD DATA field.
+func bulkLoadHexValue(value interface{}) string {
+	if value == nil {
+		return `\N`
+	}
+	return hex.EncodeToString([]byte(bulkLoadText(value)))
+}
+
+// bulkLoadText returns the text of a non-nil fixture value as MySQL reads it.
+func bulkLoadText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isLocalInfileDisabled(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == mysqlErrNotAllowedCommand || mysqlErr.Number == mysqlErrClientLocalFileDisabled
+}
@@ -0,0 +1,52 @@
+package fixturer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBulkLoadBinaryColumns(t *testing.T) {
+	columns := []string{"id", "name", "data"}
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "Ann\tLee", "data": []byte{0xff, 0x00, 0xc3, 0x28}},
+		{"id": 2, "name": "Bob", "data": nil},
+	}
+	binary := binaryColumns(columns, rows)
+
+	want := "LOAD DATA LOCAL INFILE 'Reader::r' INTO TABLE `files` CHARACTER SET utf8mb4 " +
+		`FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n' ` +
+		"(`id`, `name`, @fixturer_2) SET `data` = UNHEX(@fixturer_2)"
+	if got := bulkLoadStatement("r", "files", columns, binary); got != want {
+		t.Errorf("got statement %q, want %q", got, want)
+	}
+
+	var encoded bytes.Buffer
+	if err := writeBulkLoadRows(&encoded, columns, binary, rows); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\tAnn\\tLee\tff00c328\n2\tBob\t\\N\n"; encoded.String() != want {
+		t.Errorf("got rows %q, want %q", encoded.String(), want)
+	}
+}
+
+func TestBulkLoads(t *testing.T) {
+	columns := []string{"id", "name"}
+	full := []map[string]interface{}{{"id": 1, "name": "Ann"}}
+	sparse := []map[string]interface{}{{"id": 1, "name": "Ann"}, {"id": 2}}
+	for _, test := range []struct {
+		name string
+		f    IFixturer
+		rows []map[string]interface{}
+		want bool
+	}{
+		{"disabled", NewFixturer("", "", "", "test", ""), full, false},
+		{"enabled", NewFixturer("", "", "", "test", "").WithBulkLoad(true), full, true},
+		{"postgres", NewFixturer("", "", "", "test", "").WithBulkLoad(true).WithDialect(PostgresDialect{}), full, false},
+		{"upsert", NewFixturer("", "", "", "test", "").WithBulkLoad(true).WithUpsert(true), full, false},
+		{"sparse rows", NewFixturer("", "", "", "test", "").WithBulkLoad(true), sparse, false},
+	} {
+		if got := test.f.(*Fixturer).bulkLoads("users", columns, test.rows); got != test.want {
+			t.Errorf("%s: got bulk load %t, want %t", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package fixturer
+
+// fixturesCache keeps the parsed fixtures of a fixtures source, so the next imports of the source
+// parse nothing. Every fixturer has its own caches.
+type fixturesCache struct {
+	// parsed is set once all the fixture files of the source are parsed while connected.
+	parsed bool
+	// tables are the parsed tables in the order of the files.
+	tables []string
+	// queries are the parsed fixtures by the file path, or the path and "#" and the table of a multi-table file.
+	queries map[string]*insertQuery
+}
+
+// cache returns the cache of the current fixtures source.
+func (this *Fixturer) cache() *fixturesCache {
+	this.cachesMutex.Lock()
+	defer this.cachesMutex.Unlock()
+
+	cache, find := this.caches[this.fixturesSource()]
+	if !find {
+		cache = &fixturesCache{queries: map[string]*insertQuery{}}
+		this.caches[this.fixturesSource()] = cache
+	}
+	return cache
+}
+
+// dropCaches forgets the parsed fixtures of every fixtures source. Every setter changing how the fixtures are
+// parsed or the inserts are built calls it, so the next import does not reuse the stale statements.
+func (this *Fixturer) dropCaches() {
+	this.cachesMutex.Lock()
+	defer this.cachesMutex.Unlock()
+	this.caches = map[string]*fixturesCache{}
+}
+
+// dropCache forgets the parsed fixtures of the current fixtures source.
+func (this *Fixturer) dropCache() {
+	this.cachesMutex.Lock()
+	defer this.cachesMutex.Unlock()
+	delete(this.caches, this.fixturesSource())
+}
@@ -0,0 +1,59 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RecreateTableDialect is a Dialect able to drop a table and create it again for ClearRecreate.
+type RecreateTableDialect interface {
+	Dialect
+	// CreateTableQuery returns the query of the table name and the statement creating the table.
+	CreateTableQuery(quotedTable string) string
+	// CreateTable returns the statement creating the empty table from the one read by CreateTableQuery.
+	CreateTable(statement string) string
+}
+
+// clearStrategyNames are the clear strategies of the manifest by their names.
+var clearStrategyNames = map[string]ClearStrategy{
+	"truncate": ClearTruncate,
+	"delete":   ClearDelete,
+	"recreate": ClearRecreate,
+}
+
+// WithTableClearStrategy sets the way the table is cleared, overriding WithClearStrategy for the table,
+// e.g. ClearDelete for a table whose auto-increment counter must not be reset. The manifest setting
+// of the table, if any, takes precedence.
+func (this *Fixturer) WithTableClearStrategy(table string, strategy ClearStrategy) IFixturer {
+	this.clearStrategies[table] = strategy
+	return this
+}
+
+// tableClearStrategy returns the way the table is cleared.
+func (this *Fixturer) tableClearStrategy(table string) ClearStrategy {
+	if strategy, find := clearStrategyNames[this.tableOptions(table).clear]; find {
+		return strategy
+	}
+	if strategy, find := this.clearStrategies[table]; find {
+		return strategy
+	}
+	return this.clearStrategy
+}
+
+// recreateTable drops the table and creates it again with the statement the database reports for it.
+func (this *Fixturer) recreateTable(ctx context.Context, conn *sql.Conn, table string) error {
+	d, ok := this.dialect.(RecreateTableDialect)
+	if !ok {
+		return fmt.Errorf("dialect %T can't recreate table %s", this.dialect, table)
+	}
+	var name, statement string
+	if err := conn.QueryRowContext(ctx, d.CreateTableQuery(this.quoteTable(table))).Scan(&name, &statement); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "DROP TABLE "+this.quoteTable(table)); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, d.CreateTable(statement))
+	return err
+}
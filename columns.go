@@ -0,0 +1,102 @@
+package fixturer
+
+// SetIgnoredColumns sets the columns of the table stripped from the fixture rows before the insert is built,
+// e.g. the generated columns which must not be inserted. The generated columns of the tables are discovered
+// from information_schema when connected and stripped as well.
+func (this *Fixturer) SetIgnoredColumns(table string, columns []string) IFixturer {
+	this.ignoredColumns[table] = map[string]struct{}{}
+	for _, column := range columns {
+		this.ignoredColumns[table][column] = struct{}{}
+	}
+	this.dropCaches()
+	return this
+}
+
+// WithColumns sets the only columns of the table inserted from the fixture rows, the other keys of the rows,
+// e.g. the documentation fields, are dropped. The generated and ignored columns are stripped anyway.
+func (this *Fixturer) WithColumns(table string, columns []string) IFixturer {
+	this.allowedColumns[table] = map[string]struct{}{}
+	for _, column := range columns {
+		this.allowedColumns[table][column] = struct{}{}
+	}
+	this.dropCaches()
+	return this
+}
+
+// discoverGeneratedColumns reads the generated columns of the database tables, if connected.
+func (this *Fixturer) discoverGeneratedColumns() (err error) {
+	this.generatedColumns, err = this.queryTablesColumns(this.dialect.GeneratedColumnsQuery())
+	return err
+}
+
+// discoverNumericTextColumns reads the DECIMAL and BIGINT columns of the database tables, if connected.
+// Their fixture numbers are bound as the text, so they keep the exact value written in the fixture.
+func (this *Fixturer) discoverNumericTextColumns() (err error) {
+	this.numericTextColumns, err = this.queryTablesColumns(this.dialect.NumericColumnsQuery())
+	return err
+}
+
+// discoverIdentityColumns reads the identity columns of the database tables, if connected
+// and the dialect needs their explicit values allowed.
+func (this *Fixturer) discoverIdentityColumns() (err error) {
+	this.identityColumns = map[string]map[string]struct{}{}
+	if d, ok := this.dialect.(IdentityInsertDialect); ok {
+		this.identityColumns, err = this.queryTablesColumns(d.IdentityColumnsQuery())
+	}
+	return err
+}
+
+// queryTablesColumns runs the query of the table and column pairs and returns the columns by the table.
+// Nothing is returned when not connected.
+func (this *Fixturer) queryTablesColumns(query string) (map[string]map[string]struct{}, error) {
+	columns := map[string]map[string]struct{}{}
+	q := this.introspector()
+	if q == nil {
+		return columns, nil
+	}
+	rows, err := q.Query(query)
+	if err != nil {
+		return columns, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return columns, err
+		}
+		if columns[table] == nil {
+			columns[table] = map[string]struct{}{}
+		}
+		columns[table][column] = struct{}{}
+	}
+	return columns, rows.Err()
+}
+
+// hasIdentityColumn reports whether the columns include the identity column of the table.
+func (this *Fixturer) hasIdentityColumn(table string, columns []string) bool {
+	for _, column := range columns {
+		if _, find := this.identityColumns[table][column]; find {
+			return true
+		}
+	}
+	return false
+}
+
+// stripIgnoredColumns removes the ignored and the generated columns of the table from the row,
+// as well as the columns out of its allow-list, if any.
+func (this *Fixturer) stripIgnoredColumns(table string, row map[string]interface{}) {
+	if allowed, find := this.allowedColumns[table]; find {
+		for column := range row {
+			if _, find := allowed[column]; !find {
+				delete(row, column)
+			}
+		}
+	}
+	for column := range this.ignoredColumns[table] {
+		delete(row, column)
+	}
+	for column := range this.generatedColumns[table] {
+		delete(row, column)
+	}
+}
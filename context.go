@@ -0,0 +1,32 @@
+package fixturer
+
+import "context"
+
+// The Context variants of the operations cancel the statements and roll the transactions back when the context
+// is done. The error of a cancelled operation wraps the error of the context, or is *TimeoutError for
+// the timeout set by WithTimeout, which bounds the context of the caller as well.
+
+// RecreateDatabaseWithSchemaAndImportFixturesContext is RecreateDatabaseWithSchemaAndImportFixtures with the context.
+func (this *Fixturer) RecreateDatabaseWithSchemaAndImportFixturesContext(ctx context.Context) error {
+	return this.withContext(ctx, "recreate database with schema and import fixtures", this.recreateDatabaseWithSchemaAndImportFixtures)
+}
+
+// RecreateDatabaseContext is RecreateDatabase with the context.
+func (this *Fixturer) RecreateDatabaseContext(ctx context.Context) error {
+	return this.withContext(ctx, "recreate database", this.recreateServerDatabase)
+}
+
+// DropDatabaseContext is DropDatabase with the context.
+func (this *Fixturer) DropDatabaseContext(ctx context.Context) error {
+	return this.withContext(ctx, "drop database", this.dropDatabase)
+}
+
+// LoadDbSchemaContext is LoadDbSchema with the context.
+func (this *Fixturer) LoadDbSchemaContext(ctx context.Context) error {
+	return this.withContext(ctx, "load database schema", this.loadDbSchema)
+}
+
+// ImportFixturesContext is ImportFixtures with the context.
+func (this *Fixturer) ImportFixturesContext(ctx context.Context) error {
+	return this.withContext(ctx, "import fixtures", this.importFixtures)
+}
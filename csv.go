@@ -0,0 +1,53 @@
+package fixturer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// decodeCSVTables decodes the content of a .csv fixture, the header row names the columns and every next
+// record is a row, e.g. exported from a spreadsheet. The values are inserted as the strings, which the database
+// converts to the column types, and the empty cells as NULL.
+func (this *fixtureDecoder) decodeCSVTables(data []byte) ([]fixtureTable, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []fixtureTable{{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{}
+	for i, column := range header {
+		if column == "" {
+			return nil, fmt.Errorf("header column %d has no name", i+1)
+		}
+		if _, find := seen[column]; find {
+			return nil, fmt.Errorf("header column %s is duplicated", column)
+		}
+		seen[column] = struct{}{}
+	}
+
+	var rows []map[string]interface{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(header))
+		for i, value := range record {
+			if value == "" {
+				row[header[i]] = nil
+				continue
+			}
+			row[header[i]] = value
+		}
+		rows = append(rows, row)
+	}
+	return []fixtureTable{{rows: rows}}, nil
+}
@@ -0,0 +1,27 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCSVFixtures(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users.csv": "id,name,email\n1,\"Doe, John\",\n2,Jane,jane@example.com\n",
+	})
+	f := NewFixturer("", "", dir, "test", "")
+
+	query, args, err := f.BuildSQL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[map[email:<nil> id:1 name:Doe, John] map[email:jane@example.com id:2 name:Jane]]"
+	if got := fmt.Sprint(insertedRows(query, args)); got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	dir = writeFixtures(t, map[string]string{"users.csv": "id,id\n1,2\n"})
+	if _, _, err := NewFixturer("", "", dir, "test", "").BuildSQL("users"); err == nil {
+		t.Error("got no error for the duplicated header column")
+	}
+}
@@ -0,0 +1,447 @@
+package fixturer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Custom tags of the fixture values.
+const (
+	// tagBase64 decodes a base64 string to []byte, e.g. `avatar: !base64 iVBORw0KGgo=`.
+	tagBase64 = "!base64"
+	// tagHex decodes a hex string to []byte, e.g. `hash: !hex 00ff10`.
+	tagHex = "!hex"
+	// tagInclude splices the content of another YAML file, resolved relative to the including file,
+	// e.g. `- !include ./shared/permissions.yml` or `<<: !include ./shared/address.yml`.
+	// A list included into a list is flattened.
+	tagInclude = "!include"
+	// tagRef references the key of a row labeled in any fixture, e.g. `user_id: !ref admin`.
+	tagRef = "!ref"
+	// tagDecimal and tagInt keep the number as its text, e.g. `amount: !decimal 1.10` or `id: !int 9223372036854775807`.
+	tagDecimal = "!decimal"
+	tagInt     = "!int"
+	// tagJSON encodes a map or a list as JSON text for a JSON column, e.g. `settings: !json {theme: dark}`.
+	// A tagged string is taken as JSON text as is, e.g. `settings: !json '{"theme": "dark"}'`.
+	tagJSON = "!json"
+	// tagDefault inserts the default of the column, e.g. `created_at: !default`. The null values,
+	// e.g. `deleted_at: null` or `deleted_at: ~`, are inserted as NULL.
+	tagDefault = "!default"
+
+	yamlMergeTag = "!!merge"
+)
+
+var (
+	decimalRegexp = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+	intRegexp     = regexp.MustCompile(`^[-+]?\d+$`)
+)
+
+// fixtureDecoder converts the YAML nodes of a file to Go values.
+type fixtureDecoder struct {
+	path string
+	// including is the chain of the files including the current one.
+	including []string
+	// strict rejects the rows with nested maps or lists as the values.
+	strict bool
+	// table is the table the rows are decoded for, numericText are the columns of the tables
+	// whose numbers are kept as their text.
+	table       string
+	numericText map[string]map[string]struct{}
+}
+
+// fixtureTable is the rows of a table decoded from a fixture file.
+type fixtureTable struct {
+	// table is empty for a plain fixture loaded into the table named after the file.
+	table      string
+	rows       []map[string]interface{}
+	multiTable bool
+}
+
+// decodeTables decodes the content of the fixture file, the decoder table is the one named after the file. A plain fixture is a list of rows,
+// a multi-table fixture is a map of the table names to their lists of rows, e.g.
+//
+//	users:
+//	  - id: 1
+//	user_profiles:
+//	  - user_id: 1
+//
+// The tables of a multi-table fixture are returned in the declaration order. Streamed files are always plain fixtures.
+// A file of several YAML documents separated by --- may mix both, the rows of a table declared by several documents
+// are concatenated.
+// The .json fixtures are decoded as JSON of the same shape, see decodeJSONTables, the .csv ones see decodeCSVTables.
+// In the strict mode a plain fixture must be a list of flat rows.
+func (this *fixtureDecoder) decodeTables(data []byte) ([]fixtureTable, error) {
+	if isJSONFixture(this.path) {
+		return this.decodeJSONTables(data)
+	}
+	if isCSVFixture(this.path) {
+		return this.decodeCSVTables(data)
+	}
+
+	var tables []fixtureTable
+	// index is the position of the table in tables, the plain rows are of the table "".
+	index := map[string]int{}
+	add := func(table fixtureTable) {
+		if i, find := index[table.table]; find {
+			tables[i].rows = append(tables[i].rows, table.rows...)
+			return
+		}
+		index[table.table] = len(tables)
+		tables = append(tables, table)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		documentTables, err := this.documentTables(&document)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range documentTables {
+			add(table)
+		}
+	}
+	if len(tables) == 0 {
+		return []fixtureTable{{}}, nil
+	}
+	return tables, nil
+}
+
+// documentTables decodes a YAML document of the fixture file.
+func (this *fixtureDecoder) documentTables(document *yaml.Node) ([]fixtureTable, error) {
+	if root := multiTableRoot(document); root != nil {
+		tables := make([]fixtureTable, 0, len(root.Content)/2)
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			decoder := *this
+			decoder.table = root.Content[i].Value
+			rows, err := decoder.nodeRows(root.Content[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", root.Content[i].Value, err)
+			}
+			tables = append(tables, fixtureTable{table: root.Content[i].Value, rows: rows, multiTable: true})
+		}
+		return tables, nil
+	}
+
+	if this.strict && len(document.Content) > 0 && document.Content[0].Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: fixture must be a list of rows or a map of tables to their rows", document.Content[0].Line)
+	}
+	rows, err := this.nodeRows(document)
+	if err != nil {
+		return nil, err
+	}
+	return []fixtureTable{{rows: rows}}, nil
+}
+
+// multiTableRoot returns the root mapping of a multi-table fixture, a map with the lists as the values.
+func multiTableRoot(document *yaml.Node) *yaml.Node {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Tag == yamlMergeTag || root.Content[i+1].Kind != yaml.SequenceNode {
+			return nil
+		}
+	}
+	return root
+}
+
+// nodeRows converts a decoded YAML document to the rows.
+func (this *fixtureDecoder) nodeRows(document *yaml.Node) ([]map[string]interface{}, error) {
+	value, err := this.nodeValue(document)
+	if err != nil {
+		return nil, err
+	}
+	return this.valueRows(value)
+}
+
+// valueRows converts a decoded document, either a row or a list of rows, to the rows.
+func (this *fixtureDecoder) valueRows(value interface{}) ([]map[string]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		if err := this.checkFlatRow(v); err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("row %d must be a map, got %T", i, item)
+			}
+			if err := this.checkFlatRow(row); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("document must be a row or a list of rows, got %T", value)
+	}
+}
+
+// checkFlatRow makes sure the row has no nested maps or lists in the strict mode.
+func (this *fixtureDecoder) checkFlatRow(row map[string]interface{}) error {
+	if !this.strict {
+		return nil
+	}
+	for column, value := range row {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("column %s must be a scalar, got %T, tag it with %s for a JSON column", column, value, tagJSON)
+		}
+	}
+	return nil
+}
+
+// jsonText encodes the value as JSON text.
+func jsonText(value interface{}) (string, error) {
+	text, err := json.Marshal(value)
+	return string(text), err
+}
+
+// encodeJSONValues encodes the nested maps and lists of the row as JSON text, the driver can't bind them.
+func encodeJSONValues(row map[string]interface{}) error {
+	for column, value := range row {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			text, err := jsonText(value)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			row[column] = text
+		}
+	}
+	return nil
+}
+
+// nodeValue converts the node to a Go value resolving the custom tags.
+func (this *fixtureDecoder) nodeValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case 0:
+		return nil, nil
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return this.nodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return this.nodeValue(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := this.nodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			if included, ok := value.([]interface{}); ok && item.Tag == tagInclude {
+				list = append(list, included...)
+				continue
+			}
+			list = append(list, value)
+		}
+		if node.Tag == tagJSON {
+			return jsonText(list)
+		}
+		return list, nil
+	case yaml.MappingNode:
+		value, err := this.mappingValue(node)
+		if err != nil || node.Tag != tagJSON {
+			return value, err
+		}
+		return jsonText(value)
+	default:
+		if node.Tag == tagInclude {
+			return this.include(node)
+		}
+		return scalarValue(node)
+	}
+}
+
+// utf8BOM is the byte order mark some editors put at the beginning of UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// fixtureText strips the leading UTF-8 byte order mark of the fixture content and rejects the content
+// which is not UTF-8, e.g. saved as UTF-16 or in a legacy code page.
+func fixtureText(path string, data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if err := checkNotUTF16(path, data); err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%s is not valid UTF-8", path)
+	}
+	return data, nil
+}
+
+// checkNotUTF16 fails if the fixture content starts with a UTF-16 byte order mark.
+func checkNotUTF16(path string, data []byte) error {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return fmt.Errorf("%s is UTF-16 encoded, fixtures must be UTF-8", path)
+	}
+	return nil
+}
+
+// include decodes the file referenced by the !include node.
+func (this *fixtureDecoder) include(node *yaml.Node) (interface{}, error) {
+	path := node.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(this.path), path)
+	}
+	path = filepath.Clean(path)
+
+	chain := append(append([]string(nil), this.including...), this.path)
+	for _, including := range chain {
+		if including == path {
+			return nil, fmt.Errorf("line %d: include cycle: %s -> %s", node.Line, strings.Join(chain, " -> "), path)
+		}
+	}
+
+	data, err := readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if data, err = fixtureText(path, data); err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	included := *this
+	included.path, included.including = path, chain
+	return included.nodeValue(&document)
+}
+
+func (this *fixtureDecoder) mappingValue(node *yaml.Node) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(node.Content)/2)
+	var merged []map[string]interface{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, valueNode := node.Content[i], node.Content[i+1]
+
+		if this.keepsNumericText(key.Value, valueNode) {
+			result[key.Value] = valueNode.Value
+			continue
+		}
+		value, err := this.nodeValue(valueNode)
+		if err != nil {
+			return nil, err
+		}
+		if key.Tag == yamlMergeTag {
+			if merged, err = appendMerged(merged, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		result[key.Value] = value
+	}
+
+	// Explicit keys take precedence over the merged ones, the first merged mapping over the next ones.
+	for _, m := range merged {
+		for k, v := range m {
+			if _, find := result[k]; !find {
+				result[k] = v
+			}
+		}
+	}
+	return result, nil
+}
+
+// keepsNumericText reports whether the number is kept as its text for the column, e.g. 1.10 of a DECIMAL column,
+// so neither the trailing zeros nor the digits of a big integer are lost through float64.
+// The columns of any map of the table rows are affected, including the maps encoded as JSON.
+func (this *fixtureDecoder) keepsNumericText(column string, node *yaml.Node) bool {
+	if _, find := this.numericText[this.table][column]; !find {
+		return false
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	tag := node.ShortTag()
+	return node.Kind == yaml.ScalarNode && (tag == "!!int" || tag == "!!float")
+}
+
+func appendMerged(merged []map[string]interface{}, value interface{}) ([]map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return append(merged, v), nil
+	case []interface{}:
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("merge key value must be a map, got %T", item)
+			}
+			merged = append(merged, m)
+		}
+		return merged, nil
+	default:
+		return nil, fmt.Errorf("merge key value must be a map, got %T", value)
+	}
+}
+
+func scalarValue(node *yaml.Node) (interface{}, error) {
+	switch node.Tag {
+	case tagBase64:
+		value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s value: %w", node.Line, tagBase64, err)
+		}
+		return value, nil
+	case tagHex:
+		value, err := hex.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s value: %w", node.Line, tagHex, err)
+		}
+		return value, nil
+	case tagRef:
+		return reference{label: node.Value}, nil
+	case tagDecimal, tagInt:
+		pattern := decimalRegexp
+		if node.Tag == tagInt {
+			pattern = intRegexp
+		}
+		if !pattern.MatchString(node.Value) {
+			return nil, fmt.Errorf("line %d: invalid %s value %q", node.Line, node.Tag, node.Value)
+		}
+		return node.Value, nil
+	case tagDefault:
+		if node.Value != "" {
+			return nil, fmt.Errorf("line %d: %s takes no value, got %q", node.Line, tagDefault, node.Value)
+		}
+		return columnDefault{}, nil
+	case tagJSON:
+		if !json.Valid([]byte(node.Value)) {
+			return nil, fmt.Errorf("line %d: invalid %s value", node.Line, tagJSON)
+		}
+		return node.Value, nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
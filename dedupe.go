@@ -0,0 +1,132 @@
+package fixturer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetDedupeByPrimaryKey makes the fixture rows with the primary key of an earlier row of the same fixture dropped
+// before the insert is built, instead of failing the insert with a duplicate key error. The primary key columns
+// are the ones set by SetKeyColumns, or read from information_schema when connected, or DefaultKeyColumn.
+// The rows without all the key columns are kept. The dropped rows count is logged per table.
+func (this *Fixturer) SetDedupeByPrimaryKey(enabled bool) IFixturer {
+	this.dedupeByPrimaryKey = enabled
+	this.dropCaches()
+	return this
+}
+
+// SetDuplicatePrimaryKeyError makes the dedupe by primary key fail the import on the first duplicate
+// instead of dropping it.
+func (this *Fixturer) SetDuplicatePrimaryKeyError(enabled bool) IFixturer {
+	this.duplicateKeyError = enabled
+	this.dropCaches()
+	return this
+}
+
+// primaryKeys returns the primary key columns of the tables of the database, or nothing when not connected.
+func (this *Fixturer) primaryKeys() (map[string][]string, error) {
+	q := this.introspector()
+	if q == nil {
+		return nil, nil
+	}
+	rows, err := q.Query(this.dialect.PrimaryKeysQuery())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string][]string{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		keys[table] = append(keys[table], column)
+	}
+	return keys, rows.Err()
+}
+
+// dedupeRows drops the rows of the fixture repeating the primary key of an earlier row.
+func (this *Fixturer) dedupeRows(query *insertQuery, discovered map[string][]string) error {
+	deduper := this.newRowsDeduper(query.table, discovered)
+	rows := query.rows[:0]
+	for i, row := range query.rows {
+		keep, err := deduper.keep(i, row)
+		if err != nil {
+			return queryError(PhaseParsing, query, err)
+		}
+		if keep {
+			rows = append(rows, row)
+		}
+	}
+	query.rows = rows
+	deduper.logDropped(this, query)
+	return nil
+}
+
+// rowsDeduper tells the rows repeating the primary key of an earlier row of the fixture,
+// row by row, so the streamed fixtures are deduped as well.
+type rowsDeduper struct {
+	columns []string
+	failing bool
+	seen    map[string]int
+	dropped int
+}
+
+// newRowsDeduper makes the deduper of the table with the primary key columns set by SetKeyColumns,
+// or the discovered ones, or DefaultKeyColumn.
+func (this *Fixturer) newRowsDeduper(table string, discovered map[string][]string) *rowsDeduper {
+	columns := discovered[table]
+	if _, find := this.keyColumns[table]; find || len(columns) == 0 {
+		columns = this.tableKeyColumns(table)
+	}
+	return &rowsDeduper{columns: columns, failing: this.duplicateKeyError, seen: map[string]int{}}
+}
+
+// keep reports whether the row i is kept, the duplicate fails with SetDuplicatePrimaryKeyError.
+func (this *rowsDeduper) keep(i int, row map[string]interface{}) (bool, error) {
+	key, ok := primaryKeyValue(row, this.columns)
+	if !ok {
+		return true, nil
+	}
+	if first, find := this.seen[key]; find {
+		if this.failing {
+			return false, fmt.Errorf("row %d: duplicate primary key (%s) of row %d", i, strings.Join(this.columns, ", "), first)
+		}
+		this.dropped++
+		return false, nil
+	}
+	this.seen[key] = i
+	return true, nil
+}
+
+// logDropped logs the count of the rows dropped from the fixture, if any.
+func (this *rowsDeduper) logDropped(f *Fixturer, query *insertQuery) {
+	if this.dropped == 0 {
+		return
+	}
+	f.logger.Log(LogEvent{
+		Level:   LevelWarn,
+		Message: fmt.Sprintf("Dropped %d rows of %s with duplicate primary keys", this.dropped, query.file),
+		Phase:   PhaseParsing,
+		Table:   query.table,
+		File:    query.file,
+		Rows:    this.dropped,
+	})
+}
+
+// primaryKeyValue returns the primary key of the row as a string, or false if the row lacks a key column.
+func primaryKeyValue(row map[string]interface{}, columns []string) (string, bool) {
+	values := make([]string, 0, len(columns))
+	for _, column := range columns {
+		value, find := row[column]
+		if !find || value == nil {
+			return "", false
+		}
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		values = append(values, fmt.Sprintf("%T:%v", value, value))
+	}
+	return strings.Join(values, "\x00"), true
+}
@@ -0,0 +1,27 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDedupeByPrimaryKey(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "- id: 1\n  name: Ann\n- id: 2\n  name: Bob\n- id: 1\n  name: Dup\n- name: Keyless\n",
+	})
+	f := NewFixturer("", "", dir, "test", "").WithMissingColumns(MissingColumnsNull).SetDedupeByPrimaryKey(true)
+
+	query, args, err := f.BuildSQL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[map[id:1 name:Ann] map[id:2 name:Bob] map[id:<nil> name:Keyless]]"
+	if got := fmt.Sprint(insertedRows(query, args)); got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	f.SetDuplicatePrimaryKeyError(true)
+	if _, _, err := f.BuildSQL("users"); err == nil {
+		t.Error("got no error for the duplicate primary key")
+	}
+}
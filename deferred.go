@@ -0,0 +1,165 @@
+package fixturer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WithDeferredColumns sets the foreign key columns of the table inserted as NULL and updated with their
+// fixture values once every table is inserted, so the tables referencing each other in a cycle are loaded
+// with the foreign key checks on. The cycle is broken at the tables with deferred columns, which are
+// inserted before the tables they reference. The rows are updated by their key columns (see SetKeyColumns).
+// A streamed fixture of the table fails the import.
+func (this *Fixturer) WithDeferredColumns(table string, columns []string) IFixturer {
+	this.deferredColumns[table] = map[string]struct{}{}
+	for _, column := range columns {
+		this.deferredColumns[table][column] = struct{}{}
+	}
+	this.dropCaches()
+	return this
+}
+
+// deferredUpdate is the update setting the deferred columns of a row.
+type deferredUpdate struct {
+	query string
+	args  []interface{}
+}
+
+// deferColumns builds the updates of the deferred columns and returns the rows to insert, the copies
+// of the rows with the deferred values replaced with NULL. The rows of the query keep the fixture values.
+func (this *Fixturer) deferColumns(query *insertQuery) ([]map[string]interface{}, error) {
+	deferred := this.deferredColumns[query.table]
+	query.updates = nil
+	if len(deferred) == 0 {
+		return query.rows, nil
+	}
+	keyColumns := this.tableKeyColumns(query.table)
+
+	rows := make([]map[string]interface{}, len(query.rows))
+	for i, row := range query.rows {
+		rows[i] = row
+		var columns []string
+		for column := range deferred {
+			if value, find := row[column]; find && value != nil {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		sort.Strings(columns)
+		rows[i] = make(map[string]interface{}, len(row))
+		for column, value := range row {
+			rows[i][column] = value
+		}
+
+		set := make([]string, 0, len(columns))
+		args := make([]interface{}, 0, len(columns)+len(keyColumns))
+		for _, column := range columns {
+			set = append(set, this.quoteIdentifier(column)+" = ?")
+			args = append(args, row[column])
+			rows[i][column] = nil
+		}
+		where := make([]string, 0, len(keyColumns))
+		for _, column := range keyColumns {
+			value, find := row[column]
+			if !find || value == nil {
+				return nil, fmt.Errorf("row %d: key column %s is required to defer %s", i, column, strings.Join(columns, ", "))
+			}
+			where = append(where, this.quoteIdentifier(column)+" = ?")
+			args = append(args, value)
+		}
+
+		sql := "UPDATE " + this.quoteTable(query.table) + " SET " + strings.Join(set, ", ") + " WHERE " + strings.Join(where, " AND ")
+		if rebound, err := this.placeholderFormat.ReplacePlaceholders(sql); err == nil {
+			sql = rebound
+		}
+		query.updates = append(query.updates, deferredUpdate{query: sql, args: args})
+	}
+	return rows, nil
+}
+
+// deferredTables returns the tables with deferred columns.
+func (this *Fixturer) deferredTables() map[string]struct{} {
+	tables := make(map[string]struct{}, len(this.deferredColumns))
+	for table, columns := range this.deferredColumns {
+		if len(columns) > 0 {
+			tables[table] = struct{}{}
+		}
+	}
+	return tables
+}
+
+// hasDeferredUpdates reports whether any of the queries has the deferred columns to update.
+func hasDeferredUpdates(queries []*insertQuery) bool {
+	for _, query := range queries {
+		if len(query.updates) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// updateDeferred sets the deferred columns of the inserted queries in the worker transaction.
+func (this *insertWorker) updateDeferred(f *Fixturer, queries []*insertQuery) error {
+	for _, query := range queries {
+		if len(query.updates) == 0 {
+			continue
+		}
+		if this.tx == nil {
+			if err := this.begin(f); err != nil {
+				return err
+			}
+		}
+		if err := this.update(f, query); err != nil {
+			return queryError(PhaseInserting, query, err)
+		}
+	}
+	return nil
+}
+
+// updateDeferredInOwnTx sets the deferred columns of every query in its own committed transaction
+// and records the failures in the results of the worker. The tables failed by any worker are skipped.
+func (this *insertWorker) updateDeferredInOwnTx(f *Fixturer, queries []*insertQuery, failed map[string]error) {
+	for _, query := range queries {
+		if len(query.updates) == 0 || failed[query.table] != nil {
+			continue
+		}
+		err := this.begin(f)
+		if err == nil {
+			if err = this.update(f, query); err == nil {
+				err = this.commit(f)
+			} else {
+				this.tx.Rollback()
+			}
+		} else if this.tx != nil {
+			this.tx.Rollback()
+		}
+		this.tx = nil
+		if err != nil {
+			this.results[query.table] = queryError(PhaseInserting, query, err)
+		}
+	}
+}
+
+// update executes the deferred updates of the query.
+func (this *insertWorker) update(f *Fixturer, query *insertQuery) error {
+	start := time.Now()
+	for _, update := range query.updates {
+		if _, err := this.tx.Exec(update.query, update.args...); err != nil {
+			return err
+		}
+	}
+	f.logger.Log(LogEvent{
+		Level:    LevelDebug,
+		Message:  "Deferred columns updated",
+		Phase:    PhaseInserting,
+		Table:    query.table,
+		File:     query.file,
+		Duration: time.Since(start),
+		Rows:     len(query.updates),
+	})
+	return nil
+}
@@ -0,0 +1,36 @@
+package fixturer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDeferredColumns(t *testing.T) {
+	references := map[string]string{"users": "teams", "teams": "users"}
+	db, fake := openFakeDB(t, respondForeignKeys(references, "teams", "users"))
+	dir := writeFixtures(t, map[string]string{
+		"teams.yml": "- id: 1\n  owner_id: 1\n",
+		"users.yml": "- id: 1\n  team_id: 1\n",
+	})
+	f := newFakeFixturer(db, dir).SetDisableForeignKeyChecks(false).WithDeferredColumns("users", []string{"team_id"})
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	var order []string
+	for _, statement := range fake.executed("") {
+		if strings.HasPrefix(statement.query, "INSERT INTO") || strings.HasPrefix(statement.query, "UPDATE") {
+			order = append(order, strings.SplitN(statement.query, " (", 2)[0])
+		}
+	}
+	if got, want := strings.Join(order, ", "), "INSERT INTO `users`, INSERT INTO `teams`, UPDATE `users` SET `team_id` = ? WHERE `id` = ?"; got != want {
+		t.Errorf("got statements %s, want %s", got, want)
+	}
+	if args := insertedArgs(fake, "`users`"); !strings.Contains(args, "<nil>") {
+		t.Errorf("got users args %s, want team_id inserted as NULL", args)
+	}
+	if got, want := fmt.Sprint(fake.executed("UPDATE")[0].args), "[1 1]"; got != want {
+		t.Errorf("got update args %s, want %s", got, want)
+	}
+}
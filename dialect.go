@@ -0,0 +1,287 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Dialect is the SQL of a database the fixturer works with. The dialects of MySQL (default), PostgreSQL and SQLite
+// are registered for their drivers, the others may be added with RegisterDialect, e.g. by embedding one of them
+// and overriding what differs. The query methods return the SQL with question mark placeholders, which are
+// replaced with the ones of PlaceholderFormat, and look the tables up in the current schema.
+type Dialect interface {
+	// DriverName is the database/sql driver, registered by the import of the driver package.
+	DriverName() string
+	// DSN assembles the DSN of the database, empty dbName is the connection to the server.
+	DSN(dbConf, dbName, params string) (string, error)
+	QuoteIdentifier(name string) string
+	PlaceholderFormat() squirrel.PlaceholderFormat
+
+	// The database operations get the connection to the server and dbConf, e.g. for a database file path.
+	DropDatabase(ctx context.Context, server *sql.DB, dbConf, name string) error
+	CreateDatabase(ctx context.Context, server *sql.DB, dbConf, name string) error
+	DatabaseExists(server *sql.DB, dbConf, name string) (bool, error)
+
+	// ForeignKeyChecks switches the foreign key checks of the session.
+	ForeignKeyChecks(enabled bool) string
+	// Truncate clears the table while the foreign key checks are off.
+	Truncate(quotedTable string) string
+	// StatementTimeout returns the statements bounding the lock waits of the session (see WithStatementTimeout).
+	StatementTimeout(timeout time.Duration) []string
+	// LocalInfile reports whether LOAD DATA LOCAL INFILE is supported (see WithBulkLoad).
+	LocalInfile() bool
+	// SingleWriter reports whether the database takes a single write transaction at a time,
+	// so the tables are inserted by a single worker.
+	SingleWriter() bool
+
+	// TablesQuery returns the names of the tables.
+	TablesQuery() string
+	// TableExistsQuery counts the tables of the schema and the name given as the arguments.
+	TableExistsQuery() string
+	// ColumnsQuery returns the columns of the table of the current schema, or of the schema and the table
+	// given as the arguments when qualified.
+	ColumnsQuery(qualified bool) string
+	// ForeignKeysQuery returns the pairs of the table and the table it references.
+	ForeignKeysQuery() string
+	// PrimaryKeysQuery returns the table and column pairs of the primary keys in the order of the key columns.
+	PrimaryKeysQuery() string
+	// GeneratedColumnsQuery returns the table and column pairs of the generated columns, which are not inserted.
+	GeneratedColumnsQuery() string
+	// NumericColumnsQuery returns the table and column pairs of the exact numeric columns,
+	// whose fixture numbers are inserted as their text.
+	NumericColumnsQuery() string
+}
+
+// InsertIgnoreDialect is a Dialect able to skip the inserted rows of the existing keys (see WithInsertIgnore).
+type InsertIgnoreDialect interface {
+	Dialect
+	// IgnoreDuplicates makes the insert skip the rows of the existing keys.
+	IgnoreDuplicates(qb squirrel.InsertBuilder) squirrel.InsertBuilder
+}
+
+var (
+	dialectsMutex sync.RWMutex
+	// dialects are the dialects of the drivers by the driver name.
+	dialects = map[string]Dialect{
+		"mysql":    MySQLDialect{},
+		"postgres": PostgresDialect{Driver: "postgres"},
+		"pgx":      PostgresDialect{Driver: "pgx"},
+		"sqlite3":  SQLiteDialect{Driver: "sqlite3"},
+		"sqlite":   SQLiteDialect{Driver: "sqlite"},
+		// The driver of github.com/microsoft/go-mssqldb is registered as sqlserver and as mssql.
+		"sqlserver": SQLServerDialect{Driver: "sqlserver"},
+		"mssql":     SQLServerDialect{Driver: "mssql"},
+	}
+)
+
+// RegisterDialect registers the dialect of the driver for WithDriver, replacing the registered one, if any.
+func RegisterDialect(driver string, dialect Dialect) {
+	dialectsMutex.Lock()
+	defer dialectsMutex.Unlock()
+	dialects[driver] = dialect
+}
+
+// WithDriver sets the database/sql driver of the connections along with its registered dialect: "mysql" (default),
+// "postgres" (lib/pq) and "pgx" (pgx stdlib) for PostgreSQL, or "sqlite3" (mattn/go-sqlite3) and "sqlite"
+// (modernc.org/sqlite) for SQLite, or "sqlserver" for SQL Server. The driver package must be imported by the caller.
+// An unknown driver fails the first connection.
+func (this *Fixturer) WithDriver(driver string) IFixturer {
+	dialectsMutex.RLock()
+	d, find := dialects[driver]
+	dialectsMutex.RUnlock()
+	if !find {
+		d = unknownDialect{MySQLDialect{}, driver}
+	}
+	return this.WithDialect(d)
+}
+
+// WithDialect sets the dialect of the database, e.g. a dialect of a driver registered under several names.
+// It resets the placeholder format and the identifier quoting to the ones of the dialect, so WithPlaceholderFormat
+// and WithIdentifierQuote go after it.
+func (this *Fixturer) WithDialect(dialect Dialect) IFixturer {
+	this.dialect = dialect
+	this.placeholderFormat = dialect.PlaceholderFormat()
+	this.quoteIdentifier = dialect.QuoteIdentifier
+	this.dropCaches()
+	return this
+}
+
+// rebind replaces the question mark placeholders of the introspection query with the ones of the dialect.
+func (this *Fixturer) rebind(query string) string {
+	rebound, err := this.dialect.PlaceholderFormat().ReplacePlaceholders(query)
+	if err != nil {
+		return query
+	}
+	return rebound
+}
+
+// autoIncrementOptionRegexp matches the AUTO_INCREMENT table option of SHOW CREATE TABLE.
+var autoIncrementOptionRegexp = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// MySQLDialect is the dialect of MySQL and MariaDB.
+type MySQLDialect struct{}
+
+func (MySQLDialect) DriverName() string {
+	return "mysql"
+}
+
+func (MySQLDialect) DSN(dbConf, dbName, params string) (string, error) {
+	return buildDSN(dbConf, dbName, params)
+}
+
+func (MySQLDialect) QuoteIdentifier(name string) string {
+	return QuoteMySQLIdentifier(name)
+}
+
+func (MySQLDialect) PlaceholderFormat() squirrel.PlaceholderFormat {
+	return squirrel.Question
+}
+
+func (MySQLDialect) DropDatabase(ctx context.Context, server *sql.DB, dbConf, name string) error {
+	_, err := server.ExecContext(ctx, "DROP DATABASE IF EXISTS "+QuoteMySQLIdentifier(name))
+	return err
+}
+
+func (MySQLDialect) CreateDatabase(ctx context.Context, server *sql.DB, dbConf, name string) error {
+	_, err := server.ExecContext(ctx, "CREATE DATABASE "+QuoteMySQLIdentifier(name))
+	return err
+}
+
+func (MySQLDialect) DatabaseExists(server *sql.DB, dbConf, name string) (bool, error) {
+	var cnt int
+	err := server.QueryRow("SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", name).Scan(&cnt)
+	return cnt > 0, err
+}
+
+func (MySQLDialect) ForeignKeyChecks(enabled bool) string {
+	if enabled {
+		return "SET FOREIGN_KEY_CHECKS=1"
+	}
+	return "SET FOREIGN_KEY_CHECKS=0"
+}
+
+func (MySQLDialect) Truncate(quotedTable string) string {
+	return "TRUNCATE " + quotedTable
+}
+
+func (MySQLDialect) StatementTimeout(timeout time.Duration) []string {
+	seconds := int64(math.Ceil(timeout.Seconds()))
+	return []string{
+		fmt.Sprintf("SET SESSION innodb_lock_wait_timeout = %d", seconds),
+		fmt.Sprintf("SET SESSION lock_wait_timeout = %d", seconds),
+		fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds()),
+	}
+}
+
+func (MySQLDialect) IgnoreDuplicates(qb squirrel.InsertBuilder) squirrel.InsertBuilder {
+	return qb.Options("IGNORE")
+}
+
+// Upsert updates the columns with their inserted values. Without the columns to update the key is set
+// to itself, so the conflicting rows are kept as they are.
+func (MySQLDialect) Upsert(qb squirrel.InsertBuilder, quotedKeyColumns, quotedUpdateColumns []string) squirrel.InsertBuilder {
+	if len(quotedUpdateColumns) == 0 {
+		quotedUpdateColumns = quotedKeyColumns[:1]
+	}
+	set := make([]string, len(quotedUpdateColumns))
+	for i, column := range quotedUpdateColumns {
+		set[i] = column + " = VALUES(" + column + ")"
+	}
+	return qb.Suffix("ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "))
+}
+
+func (MySQLDialect) LocalInfile() bool {
+	return true
+}
+
+func (MySQLDialect) SingleWriter() bool {
+	return false
+}
+
+func (MySQLDialect) TablesQuery() string {
+	return informationSchemaTablesQuery("DATABASE()")
+}
+
+func (MySQLDialect) TableExistsQuery() string {
+	return informationSchemaTableExistsQuery
+}
+
+func (MySQLDialect) ColumnsQuery(qualified bool) string {
+	return informationSchemaColumnsQuery("DATABASE()", qualified)
+}
+
+func (MySQLDialect) ForeignKeysQuery() string {
+	return `SELECT TABLE_NAME, REFERENCED_TABLE_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL`
+}
+
+func (MySQLDialect) SelfReferencesQuery() string {
+	return `SELECT TABLE_NAME, COLUMN_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME = TABLE_NAME`
+}
+
+func (MySQLDialect) CreateTableQuery(quotedTable string) string {
+	return "SHOW CREATE TABLE " + quotedTable
+}
+
+// CreateTable drops the AUTO_INCREMENT option of the statement, so the recreated table starts the counter over.
+func (MySQLDialect) CreateTable(statement string) string {
+	return autoIncrementOptionRegexp.ReplaceAllString(statement, "")
+}
+
+func (MySQLDialect) PrimaryKeysQuery() string {
+	return `SELECT TABLE_NAME, COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`
+}
+
+func (MySQLDialect) GeneratedColumnsQuery() string {
+	return `SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND (EXTRA LIKE '%GENERATED%' OR GENERATION_EXPRESSION <> '')`
+}
+
+func (MySQLDialect) NumericColumnsQuery() string {
+	return `SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND DATA_TYPE IN ('decimal', 'bigint')`
+}
+
+// informationSchemaTableExistsQuery counts the tables of the schema and the name with the information_schema.
+const informationSchemaTableExistsQuery = "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+
+// informationSchemaTablesQuery returns the tables of the current schema with the information_schema.
+func informationSchemaTablesQuery(currentSchema string) string {
+	return "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = " + currentSchema
+}
+
+// informationSchemaColumnsQuery returns the columns of the table with the information_schema.
+func informationSchemaColumnsQuery(currentSchema string, qualified bool) string {
+	if qualified {
+		return "SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	}
+	return "SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = " + currentSchema + " AND TABLE_NAME = ?"
+}
+
+// unknownDialect is set by WithDriver for a driver without a dialect, its connections fail.
+type unknownDialect struct {
+	MySQLDialect
+	driver string
+}
+
+func (this unknownDialect) DriverName() string {
+	return this.driver
+}
+
+func (this unknownDialect) DSN(dbConf, dbName, params string) (string, error) {
+	return "", fmt.Errorf("no dialect for driver %q", this.driver)
+}
@@ -0,0 +1,183 @@
+package fixturer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableDiff is the difference between the rows of a table and its fixture rows.
+// Only the columns of the fixture rows are compared, so the columns filled by the database are not reported.
+type TableDiff struct {
+	Table string
+	// Missing are the fixture rows absent from the table.
+	Missing []map[string]interface{}
+	// Extra are the table rows absent from the fixture.
+	Extra []map[string]interface{}
+	// Changed are the rows of the same primary key with different values.
+	Changed []RowDiff
+}
+
+// RowDiff is a row of the table differing from the fixture row of the same primary key.
+type RowDiff struct {
+	Expected map[string]interface{}
+	Actual   map[string]interface{}
+}
+
+// Diff compares the rows of the fixture tables with their fixture rows and returns the tables that differ,
+// none if the database matches the fixtures. The rows are matched by the primary key, read from
+// information_schema or set by SetKeyColumns, or by all the compared values when the table has no key.
+// The values are compared as their text, e.g. true of the fixture equals 1 of the table.
+// It uses the open connection or connects for the call. The streamed fixtures are not supported.
+func (this *Fixturer) Diff() ([]TableDiff, error) {
+	if this.db == nil {
+		if err := this.ensureDbConnected(); err != nil {
+			return nil, err
+		}
+		defer this.ensureDbDisconnected()
+	}
+
+	files, err := this.fixtureFiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := this.parseFixtures(files); err != nil {
+		return nil, err
+	}
+	discovered, err := this.primaryKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := append([]string(nil), this.cache().tables...)
+	sort.Strings(tables)
+	var diffs []TableDiff
+	for _, table := range tables {
+		var expected []map[string]interface{}
+		for _, query := range this.queriesInOrder([]string{table}, true) {
+			if query.streamed {
+				return nil, fmt.Errorf("fixture %s is streamed, it can't be compared", query.file)
+			}
+			expected = append(expected, query.rows...)
+		}
+
+		keys := discovered[table]
+		if columns, find := this.keyColumns[table]; find && len(columns) > 0 {
+			keys = columns
+		}
+		actual, err := this.tableRows(table)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+
+		diff := diffRows(expected, actual, rowsColumns(expected), keys)
+		if len(diff.Missing)+len(diff.Extra)+len(diff.Changed) > 0 {
+			diff.Table = table
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+// tableRows reads all the rows of the table.
+func (this *Fixturer) tableRows(table string) ([]map[string]interface{}, error) {
+	rows, err := this.db.Query("SELECT * FROM " + this.quoteTable(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// diffRows matches the actual rows with the expected ones by the key columns, or by all the columns without keys.
+func diffRows(expected, actual []map[string]interface{}, columns, keys []string) TableDiff {
+	var diff TableDiff
+	if len(keys) == 0 {
+		keys = columns
+	}
+
+	pending := map[string][]int{}
+	for i, row := range actual {
+		key := diffKey(row, keys)
+		pending[key] = append(pending[key], i)
+	}
+	matched := make([]bool, len(actual))
+	for _, row := range expected {
+		key := diffKey(row, keys)
+		if len(pending[key]) == 0 {
+			diff.Missing = append(diff.Missing, row)
+			continue
+		}
+		i := pending[key][0]
+		pending[key] = pending[key][1:]
+		matched[i] = true
+		if diffKey(row, columns) != diffKey(actual[i], columns) {
+			diff.Changed = append(diff.Changed, RowDiff{Expected: row, Actual: actual[i]})
+		}
+	}
+	for i, row := range actual {
+		if !matched[i] {
+			diff.Extra = append(diff.Extra, row)
+		}
+	}
+	return diff
+}
+
+// diffKey joins the text of the row values of the columns.
+func diffKey(row map[string]interface{}, columns []string) string {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = diffValue(row[column])
+	}
+	return strings.Join(values, "\x00")
+}
+
+// diffValue returns the value as the text MySQL returns for it.
+func diffValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "\x00NULL"
+	case columnDefault:
+		return "\x00DEFAULT"
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	}
+	return fmt.Sprint(value)
+}
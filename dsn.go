@@ -0,0 +1,90 @@
+package fixturer
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultDSNParams are the params of the default DSN assembly, dbConf and dbParams may override them.
+const DefaultDSNParams = "parseTime=true&multiStatements=true"
+
+// WithDSNBuilder sets the function building the DSN of the connections, e.g. for a unix socket
+// or options the default assembly of dbConf, dbName and dbParams can't express.
+// The builder gets an empty dbName for the connections to the server made before the database exists.
+// The DSN it returns is used as is, so neither DefaultDSNParams nor WithSQLMode apply.
+func (this *Fixturer) WithDSNBuilder(builder func(dbName string) string) IFixturer {
+	this.dsnBuilder = builder
+	return this
+}
+
+// serverDSN returns the DSN of the connections without a selected database.
+// It keeps dbParams, e.g. tls=custom, so the server connections are made the same way as the database ones.
+func (this *Fixturer) serverDSN() (string, error) {
+	if this.dsnBuilder != nil {
+		return this.dsnBuilder(""), nil
+	}
+	return this.dialect.DSN(this.dbConf, "", this.dbParams)
+}
+
+// openServerDb opens the connections to the server without a selected database.
+func (this *Fixturer) openServerDb() (*sql.DB, error) {
+	if this.externalDb {
+		return nil, ErrExternalDB
+	}
+	dsn, err := this.serverDSN()
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open(this.dialect.DriverName(), dsn)
+}
+
+// databaseDSN returns the DSN of the connections to the fixtures database.
+func (this *Fixturer) databaseDSN() (string, error) {
+	if this.dsnBuilder != nil {
+		return this.dsnBuilder(this.dbName), nil
+	}
+
+	params := this.dbParams
+	if _, ok := this.dialect.(MySQLDialect); ok && this.sqlMode != "" {
+		// The driver runs SET for the unknown parameters on every new connection of the pool,
+		// so the mode applies to the worker connections as well.
+		params = joinDSNParams(params, "sql_mode="+url.QueryEscape("'"+strings.Replace(this.sqlMode, "'", "''", -1)+"'"))
+	}
+	return this.dialect.DSN(this.dbConf, this.dbName, params)
+}
+
+// buildDSN appends the database name and the params to dbConf, e.g. root:pass@tcp(127.0.0.1:3306)/
+// or root:pass@unix(/var/run/mysqld/mysqld.sock)/?parseTime=true. The slash before the database name
+// is added when dbConf lacks it. The params are merged over DefaultDSNParams and the params of dbConf,
+// and the result is validated and normalized by the driver.
+func buildDSN(dbConf, dbName, params string) (string, error) {
+	base, confParams := dbConf, ""
+	// The address of the unix sockets contains slashes, so the params are looked for after it.
+	if i := strings.LastIndex(dbConf, "?"); i > strings.LastIndex(dbConf, ")") {
+		base, confParams = dbConf[:i], dbConf[i+1:]
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	// The driver applies the params in order, so the later ones take precedence.
+	config, err := mysql.ParseDSN(base + dbName + "?" + joinDSNParams(DefaultDSNParams, confParams, params))
+	if err != nil {
+		return "", err
+	}
+	return config.FormatDSN(), nil
+}
+
+// joinDSNParams joins the DSN params skipping the empty ones.
+func joinDSNParams(params ...string) string {
+	var joined []string
+	for _, p := range params {
+		if p = strings.Trim(p, "&?"); p != "" {
+			joined = append(joined, p)
+		}
+	}
+	return strings.Join(joined, "&")
+}
@@ -0,0 +1,86 @@
+package fixturer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBuildDSN(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		dbConf string
+		dbName string
+		params string
+		want   string
+	}{
+		{
+			name:   "unix socket",
+			dbConf: "root:pass@unix(/var/run/mysqld/mysqld.sock)/",
+			dbName: "test",
+			want:   "root:pass@unix(/var/run/mysqld/mysqld.sock)/test?multiStatements=true&parseTime=true",
+		},
+		{
+			name:   "unix socket without slash",
+			dbConf: "root:pass@unix(/var/run/mysqld/mysqld.sock)",
+			dbName: "test",
+			want:   "root:pass@unix(/var/run/mysqld/mysqld.sock)/test?multiStatements=true&parseTime=true",
+		},
+		{
+			name:   "unix socket with question mark",
+			dbConf: "root:pass@unix(/tmp/my?sql.sock)/?charset=utf8mb4",
+			dbName: "test",
+			want:   "root:pass@unix(/tmp/my?sql.sock)/test?multiStatements=true&parseTime=true&charset=utf8mb4",
+		},
+		{
+			name:   "tcp without slash",
+			dbConf: "root:pass@tcp(127.0.0.1:3306)",
+			dbName: "test",
+			want:   "root:pass@tcp(127.0.0.1:3306)/test?multiStatements=true&parseTime=true",
+		},
+		{
+			name:   "server connection",
+			dbConf: "root:pass@tcp(127.0.0.1:3306)/",
+			want:   "root:pass@tcp(127.0.0.1:3306)/?multiStatements=true&parseTime=true",
+		},
+		{
+			name:   "dbConf params over defaults",
+			dbConf: "root:pass@tcp(127.0.0.1:3306)/?charset=utf8mb4&multiStatements=false",
+			dbName: "test",
+			want:   "root:pass@tcp(127.0.0.1:3306)/test?parseTime=true&charset=utf8mb4",
+		},
+		{
+			name:   "params over dbConf params",
+			dbConf: "root:pass@tcp(127.0.0.1:3306)/?charset=latin1&parseTime=false",
+			dbName: "test",
+			params: "charset=utf8mb4&parseTime=true",
+			want:   "root:pass@tcp(127.0.0.1:3306)/test?multiStatements=true&parseTime=true&charset=utf8mb4",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := buildDSN(test.dbConf, test.dbName, test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The driver decides the order of the params, so the parsed configs are compared.
+			gotConfig, err := mysql.ParseDSN(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantConfig, err := mysql.ParseDSN(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotConfig, wantConfig) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildDSNInvalidParams(t *testing.T) {
+	if _, err := buildDSN("root:pass@tcp(127.0.0.1:3306)/", "test", "timeout=x"); err == nil {
+		t.Error("got no error for an invalid timeout")
+	}
+}
@@ -0,0 +1,77 @@
+package fixturer
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// RecreateEnv is the environment variable overriding whether RecreateDatabaseWithSchemaAndImportFixtures
+// recreates the database, e.g. FIXTURER_RECREATE=false to keep the database of a previous run.
+// It takes precedence over SetRecreateDatabase and SetRecreateIfAbsent, an empty value is ignored.
+const RecreateEnv = "FIXTURER_RECREATE"
+
+// envRegexp matches ${NAME} and ${NAME:-default}. The bare $NAME is left intact, so the values like
+// bcrypt hashes are not mangled.
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// WithEnvExpansion makes the ${NAME} and ${NAME:-default} in the string fixture values replaced with
+// the environment variables, e.g. `base_url: "${API_BASE}"`. An undefined variable without a default
+// is replaced with an empty string unless SetFailOnUndefinedEnv is set.
+func (this *Fixturer) WithEnvExpansion(enabled bool) IFixturer {
+	this.envExpansion = enabled
+	this.dropCaches()
+	return this
+}
+
+// SetFailOnUndefinedEnv makes the env expansion fail the import on an undefined variable without a default.
+func (this *Fixturer) SetFailOnUndefinedEnv(fail bool) IFixturer {
+	this.failOnUndefinedEnv = fail
+	this.dropCaches()
+	return this
+}
+
+// expandEnv replaces the env variables in the string values of the row.
+func (this *Fixturer) expandEnv(row map[string]interface{}) error {
+	if !this.envExpansion {
+		return nil
+	}
+	for column, value := range row {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var undefined string
+		row[column] = envRegexp.ReplaceAllStringFunc(s, func(match string) string {
+			groups := envRegexp.FindStringSubmatch(match)
+			if value, find := os.LookupEnv(groups[1]); find {
+				return value
+			}
+			if len(match) > len(groups[1])+3 {
+				return groups[2]
+			}
+			if undefined == "" {
+				undefined = groups[1]
+			}
+			return ""
+		})
+		if undefined != "" && this.failOnUndefinedEnv {
+			return fmt.Errorf("column %s: environment variable %s is not defined", column, undefined)
+		}
+	}
+	return nil
+}
+
+// recreateFromEnv returns the recreation set by RecreateEnv, if any.
+func recreateFromEnv() (recreate bool, find bool, err error) {
+	value := os.Getenv(RecreateEnv)
+	if value == "" {
+		return false, false, nil
+	}
+	recreate, err = strconv.ParseBool(value)
+	if err != nil {
+		return false, false, fmt.Errorf("%s=%q is not a boolean", RecreateEnv, value)
+	}
+	return recreate, true, nil
+}
@@ -0,0 +1,29 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnvExpansion(t *testing.T) {
+	t.Setenv("FIXTURER_TEST_HOST", "api.example.com")
+	dir := writeFixtures(t, map[string]string{
+		"hooks.yml": "- id: 1\n  url: \"https://${FIXTURER_TEST_HOST}/hook\"\n" +
+			"  region: \"${FIXTURER_TEST_REGION:-eu}\"\n  secret: \"${FIXTURER_TEST_UNSET}\"\n  hash: \"$2a$10$abc\"\n",
+	})
+	f := NewFixturer("", "", dir, "test", "").WithEnvExpansion(true)
+
+	query, args, err := f.BuildSQL("hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[map[hash:$2a$10$abc id:1 region:eu secret: url:https://api.example.com/hook]]"
+	if got := fmt.Sprint(insertedRows(query, args)); got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	f.SetFailOnUndefinedEnv(true)
+	if _, _, err := f.BuildSQL("hooks"); err == nil {
+		t.Error("got no error for the undefined variable")
+	}
+}
@@ -0,0 +1,32 @@
+package fixturer
+
+import "fmt"
+
+// FixtureError is the failure of a fixture in a phase of the import, e.g.
+//
+//	var fixtureErr *FixtureError
+//	if errors.As(err, &fixtureErr) && fixtureErr.Phase == PhaseInserting { ... }
+//
+// File is empty for the errors of a table rather than a fixture, e.g. of the truncation.
+type FixtureError struct {
+	Phase ProgressPhase
+	Table string
+	File  string
+	Err   error
+}
+
+func (this *FixtureError) Error() string {
+	if this.File == "" {
+		return fmt.Sprintf("%s table %s: %v", this.Phase, this.Table, this.Err)
+	}
+	return fmt.Sprintf("%s table %s from %s: %v", this.Phase, this.Table, this.File, this.Err)
+}
+
+func (this *FixtureError) Unwrap() error {
+	return this.Err
+}
+
+// queryError wraps the error of the parsed fixture into *FixtureError.
+func queryError(phase ProgressPhase, query *insertQuery, err error) error {
+	return &FixtureError{Phase: phase, Table: query.table, File: query.file, Err: err}
+}
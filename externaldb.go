@@ -0,0 +1,21 @@
+package fixturer
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrExternalDB is returned by the operations on the server, e.g. RecreateDatabase,
+// of the fixturer made by NewFixturerWithDB.
+var ErrExternalDB = errors.New("database of the caller can't be recreated or dropped")
+
+// NewFixturerWithDB creates the fixturer working with the open database of the caller, e.g. to share the pool
+// of the test suite. The fixturer never closes it nor changes its settings, so the pool should allow
+// as many connections as the insert goroutines (see SetInsertGoroutinesCnt). The database is neither recreated
+// nor dropped: RecreateDatabase, DropDatabase and ValidateAgainstSchema return ErrExternalDB,
+// and RecreateDatabaseWithSchemaAndImportFixtures just imports the fixtures.
+func NewFixturerWithDB(db *sql.DB, schema, fixturesPathYml string) IFixturer {
+	f := NewFixturer("", schema, fixturesPathYml, "", "").(*Fixturer)
+	f.db, f.externalDb = db, true
+	return f
+}
@@ -0,0 +1,148 @@
+package fixturer
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Faker generates the realistic values of the fixture templates (see WithTemplates), e.g.
+//
+//	{{ range $i := seq 1000 }}
+//	- id: {{ $i }}
+//	  name: "{{ fake.Name }}"
+//	  email: "{{ fake.Email }}"
+//	{{ end }}
+//
+// The emails and the usernames are unique within the file. Every file gets its own Faker,
+// seeded by WithFakerSeed and the file path, so a seeded import generates the same values every time.
+type Faker struct {
+	rand *rand.Rand
+	// seq makes the unique values unique.
+	seq int
+}
+
+var (
+	fakerFirstNames = []string{"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+		"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Daniel", "Karen", "Olga", "Ivan", "Anna"}
+	fakerLastNames = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+		"Hernandez", "Lopez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin", "Lee", "Ivanova", "Petrov"}
+	fakerCompanySuffixes = []string{"Inc", "LLC", "Group", "Labs", "Systems", "Partners", "Holdings"}
+	fakerStreetSuffixes  = []string{"Street", "Avenue", "Road", "Lane", "Boulevard", "Drive", "Way"}
+	fakerCities          = []string{"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview",
+		"Salem", "Madison", "Georgetown", "Arlington", "Ashland", "Dover", "Oxford", "Milton"}
+	fakerCountries = []string{"United States", "Canada", "United Kingdom", "Germany", "France", "Spain", "Italy", "Netherlands",
+		"Poland", "Sweden", "Japan", "Australia", "Brazil", "Mexico", "India"}
+	fakerWords = []string{"alpha", "bravo", "cloud", "delta", "engine", "forest", "garden", "harbor", "island", "jungle",
+		"kernel", "lemon", "meadow", "nectar", "ocean", "pepper", "quartz", "river", "silver", "timber", "umbra", "valley",
+		"willow", "xenon", "yellow", "zephyr"}
+)
+
+// WithFakerSeed sets the seed of the template fakers, so the generated values are reproducible.
+// Zero, the default, seeds them with the current time.
+func (this *Fixturer) WithFakerSeed(seed int64) IFixturer {
+	this.fakerSeed = seed
+	this.dropCaches()
+	return this
+}
+
+// newFaker makes the faker of the fixture file.
+func (this *Fixturer) newFaker(path string) *Faker {
+	seed := this.fakerSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Faker{rand: rand.New(rand.NewSource(seed ^ int64(crc32.ChecksumIEEE([]byte(path)))))}
+}
+
+func (this *Faker) pick(values []string) string {
+	return values[this.rand.Intn(len(values))]
+}
+
+func (this *Faker) FirstName() string {
+	return this.pick(fakerFirstNames)
+}
+
+func (this *Faker) LastName() string {
+	return this.pick(fakerLastNames)
+}
+
+func (this *Faker) Name() string {
+	return this.FirstName() + " " + this.LastName()
+}
+
+// Username is unique within the file.
+func (this *Faker) Username() string {
+	this.seq++
+	return fmt.Sprintf("%s.%s%d", strings.ToLower(this.FirstName()), strings.ToLower(this.LastName()), this.seq)
+}
+
+// Email is unique within the file, its domain is reserved for the examples.
+func (this *Faker) Email() string {
+	return this.Username() + "@example.com"
+}
+
+func (this *Faker) Phone() string {
+	return fmt.Sprintf("+1-%03d-555-%04d", 200+this.rand.Intn(800), this.rand.Intn(10000))
+}
+
+func (this *Faker) Company() string {
+	return this.LastName() + " " + this.pick(fakerCompanySuffixes)
+}
+
+func (this *Faker) Street() string {
+	word := this.Word()
+	return fmt.Sprintf("%d %s %s", 1+this.rand.Intn(9999), strings.ToUpper(word[:1])+word[1:], this.pick(fakerStreetSuffixes))
+}
+
+func (this *Faker) City() string {
+	return this.pick(fakerCities)
+}
+
+func (this *Faker) Country() string {
+	return this.pick(fakerCountries)
+}
+
+func (this *Faker) Address() string {
+	return this.Street() + ", " + this.City() + ", " + this.Country()
+}
+
+func (this *Faker) Word() string {
+	return this.pick(fakerWords)
+}
+
+func (this *Faker) Sentence() string {
+	words := make([]string, 4+this.rand.Intn(6))
+	for i := range words {
+		words[i] = this.Word()
+	}
+	sentence := strings.Join(words, " ")
+	return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+}
+
+func (this *Faker) URL() string {
+	return "https://" + this.Word() + ".example.com/" + this.Word()
+}
+
+func (this *Faker) IPv4() string {
+	return fmt.Sprintf("%d.%d.%d.%d", 1+this.rand.Intn(223), this.rand.Intn(256), this.rand.Intn(256), 1+this.rand.Intn(254))
+}
+
+// Int returns an integer of [min, max).
+func (this *Faker) Int(min, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + this.rand.Intn(max-min)
+}
+
+func (this *Faker) Bool() bool {
+	return this.rand.Intn(2) == 1
+}
+
+// Date returns a date of the last year as YYYY-MM-DD.
+func (this *Faker) Date() string {
+	return time.Now().UTC().AddDate(0, 0, -this.rand.Intn(365)).Format("2006-01-02")
+}
@@ -0,0 +1,32 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFakerSeed(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "{{ range seq 3 }}\n- id: {{ . }}\n  name: \"{{ fake.Name }}\"\n  email: \"{{ fake.Email }}\"\n{{ end }}",
+	})
+	build := func(seed int64) string {
+		t.Helper()
+		query, args, err := NewFixturer("", "", dir, "test", "").WithTemplates(true).WithFakerSeed(seed).BuildSQL("users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := insertedRows(query, args)
+		if len(rows) != 3 {
+			t.Fatalf("got %d rows, want 3", len(rows))
+		}
+		return fmt.Sprint(rows)
+	}
+
+	first := build(42)
+	if second := build(42); second != first {
+		t.Errorf("got rows %s with the same seed, want %s", second, first)
+	}
+	if other := build(7); other == first {
+		t.Errorf("got the same rows %s with another seed", other)
+	}
+}
@@ -1,73 +1,337 @@
 package fixturer
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/Masterminds/squirrel"
 	_ "github.com/go-sql-driver/mysql"
+	"io/fs"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
-
-	"flag"
-	yaml "gopkg.in/yaml.v2"
+	"text/template"
+	"time"
 )
 
 type IFixturer interface {
 	RecreateDatabaseWithSchemaAndImportFixtures() error
 	RecreateDatabase() error
+	DropDatabase() error
 	LoadDbSchema() error
 	ImportFixtures() error
 
+	RecreateDatabaseWithSchemaAndImportFixturesContext(ctx context.Context) error
+	RecreateDatabaseContext(ctx context.Context) error
+	DropDatabaseContext(ctx context.Context) error
+	LoadDbSchemaContext(ctx context.Context) error
+	ImportFixturesContext(ctx context.Context) error
+	PingContext(ctx context.Context) error
+
 	SetInsertGoroutinesCnt(int) IFixturer
+	WithBulkLoad(bool) IFixturer
+	WithFixturesGlob(string) IFixturer
+	WithRecursiveFixtures(bool) IFixturer
+	WithSubdirectorySchemas(bool) IFixturer
+	WithPlaceholderFormat(squirrel.PlaceholderFormat) IFixturer
+	WithIdentifierQuote(func(string) string) IFixturer
+	WithPreImportSQLFile(string) IFixturer
+	WithPostImportSQLFile(string) IFixturer
+	WithInsertIgnore(bool) IFixturer
+	WithUpsert(bool) IFixturer
+
+	WithTxPerTable(bool) IFixturer
+	SetDisableForeignKeyChecks(bool) IFixturer
+	WithEnforceForeignKeys(bool) IFixturer
+	SetDisableSchemaForeignKeyChecks(bool) IFixturer
+	WithProgress(func(ProgressEvent)) IFixturer
+	UseDatabase(string) IFixturer
+	WithStreaming(bool) IFixturer
+	WithMaxRowsPerTable(int) IFixturer
+	WithBatchSize(int) IFixturer
+	SetRecreateIfAbsent(bool) IFixturer
+	SetRecreateDatabase(bool) IFixturer
+	SetRowTransformer(RowTransformer) IFixturer
+	WithSQLMode(string) IFixturer
+	WithAllowMissingFixturesDir(bool) IFixturer
+	WithKeepDatabaseOnFailure(bool) IFixturer
+	WithStatementTimeout(time.Duration) IFixturer
+	WithTimeout(time.Duration) IFixturer
+
+	LastImportSummary() (tables int, rows int)
+	LoadedTables() []string
+	EmptyTables() []string
+	DatabaseRecreated() bool
+
+	Reset() error
+	BuildSQL(table string) (query string, args []interface{}, err error)
+	CountRows(table string) (int, error)
+
+	SetKeyColumns(table string, columns ...string) IFixturer
+	WithDSNBuilder(builder func(dbName string) string) IFixturer
+	WithDriver(driver string) IFixturer
+	WithDialect(dialect Dialect) IFixturer
+	WithLogger(logger Logger) IFixturer
+	WithLogLevel(level LogLevel) IFixturer
+	WithValueConverter(converter ValueConverter) IFixturer
+	WithEnsureSchema(enabled bool) IFixturer
+	WithSchemaFiles(files ...string) IFixturer
+	WithStrictYAML(strict bool) IFixturer
+	SetNoTruncateTables(tables []string) IFixturer
+	Close() error
+	RefreshTable(table string) error
+	SetDedupeByPrimaryKey(enabled bool) IFixturer
+	SetDuplicatePrimaryKeyError(enabled bool) IFixturer
+	WithFixturesArchive(path string) IFixturer
+	WithFixturesFS(fsys fs.FS, dir string) IFixturer
+	WithFixtureSource(source FixtureSource) IFixturer
+	VerifySchema() error
+	ImportFixturesFrom(dir string) error
+	Validate() error
+	ImportFixturesTx(tx *sql.Tx) error
+	Ping() error
+	WithClearStrategy(strategy ClearStrategy) IFixturer
+	WithTableClearStrategy(table string, strategy ClearStrategy) IFixturer
+	SetIgnoredColumns(table string, columns []string) IFixturer
+	WithColumns(table string, columns []string) IFixturer
+	WithDeferredColumns(table string, columns []string) IFixturer
+	Diff() ([]TableDiff, error)
+	ValidateAgainstSchema() error
+	WithoutTransaction(enabled bool) IFixturer
+	WithEnvExpansion(enabled bool) IFixturer
+	SetFailOnUndefinedEnv(fail bool) IFixturer
+	WithMissingColumns(mode MissingColumns) IFixturer
+	WithTemplates(enabled bool) IFixturer
+	WithTemplateFuncs(funcs template.FuncMap) IFixturer
+	WithFakerSeed(seed int64) IFixturer
 }
 
 type Fixturer struct {
 	db                  *sql.DB
+	externalDb          bool
+	caches              map[string]*fixturesCache
+	cachesMutex         sync.Mutex
 	dbConf              string
 	schema              string
 	fixturesPathYml     string
+	fixturesGlob        string
+	fixturesArchive     string
+	fixturesFS          fs.FS
+	fixturesFSDir       string
+	fixtureSource       FixtureSource
+	sourceFiles         []fixtureFile
+	recursiveFixtures   bool
+	subdirectorySchemas bool
 	recreateDatabase    bool
 	dbName              string
 	dbParams            string
 	insertGoroutinesCnt int
+	bulkLoad            bool
+	dialect             Dialect
+	placeholderFormat   squirrel.PlaceholderFormat
+	quoteIdentifier     func(string) string
+	preImportSQLFile    string
+	postImportSQLFile   string
+	insertIgnore        bool
+	upsert              bool
+	txPerTable          bool
+	disableForeignKeys  bool
+	disableSchemaFks    bool
+	manifest            *manifest
+	tablesOptions       map[string]tableOptions
+	progress            progressReporter
+	streaming           bool
+	maxRowsPerTable     int
+	defaultBatchSize    int
+	recreateIfAbsent    bool
+	rowTransformer      RowTransformer
+	valueConverter      ValueConverter
+	sqlMode             string
+	allowMissingDir     bool
+	keepOnFailure       bool
+	statementTimeout    time.Duration
+	keyColumns          map[string][]string
+	labels              map[string]labeledRow
+	dsnBuilder          func(dbName string) string
+	logger              Logger
+	ensureSchema        bool
+	schemaFiles         []string
+	strictYAML          bool
+	templates           bool
+	templateFuncs       template.FuncMap
+	fakerSeed           int64
+	missingColumns      MissingColumns
+	noTruncateTables    map[string]struct{}
+	dedupeByPrimaryKey  bool
+	duplicateKeyError   bool
+	clearStrategy       ClearStrategy
+	clearStrategies     map[string]ClearStrategy
+	ignoredColumns      map[string]map[string]struct{}
+	allowedColumns      map[string]map[string]struct{}
+	deferredColumns     map[string]map[string]struct{}
+	generatedColumns    map[string]map[string]struct{}
+	identityColumns     map[string]map[string]struct{}
+	selfReferences      map[string][]selfReference
+	introspectionTx     *sql.Tx
+	withoutTransaction  bool
+	envExpansion        bool
+	failOnUndefinedEnv  bool
+	numericTextColumns  map[string]map[string]struct{}
+	timeout             time.Duration
+	ctx                 context.Context
+
+	lastImportTables []string
+	lastImportRows   int
+	lastEmptyTables  []string
+	recreated        bool
+}
+
+// RowTransformer may change a fixture row before it is inserted, e.g. to hash a password or compute
+// a derived column. Returning an error aborts the import.
+type RowTransformer func(table string, row map[string]interface{}) (map[string]interface{}, error)
+
+// ValueConverter may change a fixture value before it is bound to the insert, e.g. to turn a label
+// into the stored representation. Returning an error aborts the import.
+type ValueConverter func(table, column string, raw interface{}) (interface{}, error)
+
+// fixtureFile is a fixture found by getYmlFilesList or globFiles.
+type fixtureFile struct {
+	os.FileInfo
+	path string
+	// data is the content of an archive entry, the other fixtures are read from the path.
+	data []byte
+	// schema qualifies the table of a fixture found in a subdirectory (see WithSubdirectorySchemas).
+	schema string
+}
+
+// tableName returns the table the fixture is loaded into.
+func (this fixtureFile) tableName() string {
+	table := strings.TrimSuffix(this.Name(), fixtureExt(this.Name()))
+	if this.schema != "" {
+		return this.schema + "." + table
+	}
+	return table
+}
+
+// checkDuplicateTables makes sure no two fixture files are loaded into the same table.
+func checkDuplicateTables(files []fixtureFile) error {
+	tablesFiles := map[string][]string{}
+	for _, file := range files {
+		tablesFiles[file.tableName()] = append(tablesFiles[file.tableName()], file.path)
+	}
+
+	var duplicates []string
+	for table, paths := range tablesFiles {
+		if len(paths) > 1 {
+			duplicates = append(duplicates, fmt.Sprintf("%s (%s)", table, strings.Join(paths, ", ")))
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	sort.Strings(duplicates)
+	return fmt.Errorf("several fixture files for the same table: %s", strings.Join(duplicates, "; "))
 }
 
+// checkDuplicateQueries makes sure the tables of the multi-table fixtures are not loaded by other fixtures too.
+func checkDuplicateQueries(queries []*insertQuery) error {
+	tablesFiles := map[string][]string{}
+	for _, query := range queries {
+		tablesFiles[query.table] = append(tablesFiles[query.table], query.file)
+	}
+
+	var duplicates []string
+	for table, paths := range tablesFiles {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			duplicates = append(duplicates, fmt.Sprintf("%s (%s)", table, strings.Join(paths, ", ")))
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	sort.Strings(duplicates)
+	return fmt.Errorf("several fixtures for the same table: %s", strings.Join(duplicates, "; "))
+}
+
+// insertQuery keeps the parsed rows of a fixture file next to the inserts built from them.
+// The rows are split into several inserts when the table has a batch size.
+// A streamed fixture keeps no rows, they are decoded and inserted batch by batch at load time.
 type insertQuery struct {
-	qb   *squirrel.InsertBuilder
-	file string
+	qbs     []squirrel.InsertBuilder
+	file    string
+	table   string
+	columns []string
+	rows    []map[string]interface{}
+
+	streamed     bool
+	streamedRows int
+	// multiTable is set for the tables of a multi-table fixture, inserted in the declaration order.
+	multiTable bool
+	// updates set the deferred columns of the rows after every table is inserted.
+	updates []deferredUpdate
+}
+
+// empty reports whether there is nothing to insert for the fixture.
+func (this *insertQuery) empty() bool {
+	return len(this.qbs) == 0 && !this.streamed
+}
+
+func (this *insertQuery) rowsCount() int {
+	if this.streamed {
+		return this.streamedRows
+	}
+	return len(this.rows)
 }
 
 const (
 	InsertChannelCapacity      = 1000
 	InsertGoroutinesDefaultCnt = 20
-)
-
-var (
-	finishedTablseNames = []string{}
-	finishedParsedDirs  = map[string]struct{}{}
-	insertMap           = map[string]*squirrel.InsertBuilder{}
-	recreateDatabase    = flag.Bool("recreateDatabase", true, "Do i need to recreate the database? default - true")
+	// StreamingFileSizeThreshold is the minimal size of a fixture file to be streamed when the streaming is enabled.
+	StreamingFileSizeThreshold = 32 << 20
+	// StreamingBatchSize is the rows count of a streamed table insert unless there is a batch size (see WithBatchSize).
+	StreamingBatchSize = 1000
+	// BulkLoadRowsThreshold is the minimal rows count of a table to be loaded with LOAD DATA LOCAL INFILE
+	// when the bulk load is enabled.
+	BulkLoadRowsThreshold = 1000
 )
 
 // NewFixturer create and returns new instance of &Fixturer.
-// example dbConf root:222333@tcp(127.0.0.1:3306)/
+// example dbConf root:222333@tcp(127.0.0.1:3306)/ or root:222333@unix(/var/run/mysqld/mysqld.sock)/?parseTime=true
 func NewFixturer(dbConf, schema, fixturesPathYml, dbName, dbParams string) IFixturer {
 	return &Fixturer{
 		db:               nil,
 		dbConf:           dbConf,
 		schema:           schema,
 		fixturesPathYml:  fixturesPathYml,
-		recreateDatabase: *recreateDatabase,
+		recreateDatabase: true,
 		dbName:           dbName,
 		dbParams:         dbParams,
 
 		insertGoroutinesCnt: InsertGoroutinesDefaultCnt,
+		disableForeignKeys:  true,
+		disableSchemaFks:    true,
+		tablesOptions:       map[string]tableOptions{},
+		clearStrategies:     map[string]ClearStrategy{},
+		caches:              map[string]*fixturesCache{},
+		keyColumns:          map[string][]string{},
+		ignoredColumns:      map[string]map[string]struct{}{},
+		deferredColumns:     map[string]map[string]struct{}{},
+		allowedColumns:      map[string]map[string]struct{}{},
+		labels:              map[string]labeledRow{},
+		dialect:             MySQLDialect{},
+		placeholderFormat:   squirrel.Question,
+		quoteIdentifier:     QuoteMySQLIdentifier,
+		logger:              stdLogger{level: LevelInfo},
 	}
 }
 
-// SetInsertGoroutinesCnt sets count of goroutines to perform table inserts.
+// SetInsertGoroutinesCnt sets count of goroutines to perform table inserts, each in its own transaction.
+// The tables ordered by foreign keys are inserted concurrently level by level only with WithTxPerTable
+// or WithoutTransaction, otherwise a single goroutine inserts them.
 func (this *Fixturer) SetInsertGoroutinesCnt(cnt int) IFixturer {
 	if cnt < 1 {
 		panic("Insert goroutines count must be > 1.")
@@ -76,13 +340,339 @@ func (this *Fixturer) SetInsertGoroutinesCnt(cnt int) IFixturer {
 	return this
 }
 
+// WithBulkLoad enables loading of the tables with at least BulkLoadRowsThreshold rows
+// via LOAD DATA LOCAL INFILE instead of the multi-row INSERT. The rows are streamed to the server as they are
+// encoded, and every batch of a streamed fixture (see WithStreaming) is loaded the same way.
+func (this *Fixturer) WithBulkLoad(enabled bool) IFixturer {
+	this.bulkLoad = enabled
+	return this
+}
+
+// WithFixturesGlob loads the fixtures matching the pattern instead of every .yml, .json and .csv file of the fixtures directory.
+// Besides the filepath.Match syntax the pattern may contain "**" elements matching any number of directories,
+// e.g. fixtures/**/*.users.yml.
+func (this *Fixturer) WithFixturesGlob(pattern string) IFixturer {
+	this.fixturesGlob = pattern
+	return this
+}
+
+// WithPlaceholderFormat sets the placeholder format of the generated inserts. Default is squirrel.Question (MySQL).
+func (this *Fixturer) WithPlaceholderFormat(format squirrel.PlaceholderFormat) IFixturer {
+	this.placeholderFormat = format
+	this.dropCaches()
+	return this
+}
+
+// WithIdentifierQuote sets the function quoting table and column names in the generated statements.
+// Default is QuoteMySQLIdentifier.
+func (this *Fixturer) WithIdentifierQuote(quote func(string) string) IFixturer {
+	this.quoteIdentifier = quote
+	this.dropCaches()
+	return this
+}
+
+// WithPreImportSQLFile sets the SQL file executed on the fixturer connection before the fixtures are inserted.
+func (this *Fixturer) WithPreImportSQLFile(path string) IFixturer {
+	this.preImportSQLFile = path
+	return this
+}
+
+// WithPostImportSQLFile sets the SQL file executed on the fixturer connection after the fixtures are committed,
+// e.g. to ANALYZE the tables or refresh summary tables.
+func (this *Fixturer) WithPostImportSQLFile(path string) IFixturer {
+	this.postImportSQLFile = path
+	return this
+}
+
+// WithInsertIgnore makes the inserts skip the rows conflicting with existing ones (INSERT IGNORE).
+// The tables are not truncated in this mode, so the fixtures only add the missing rows.
+// The import fails if the dialect is not an InsertIgnoreDialect, e.g. of SQL Server.
+func (this *Fixturer) WithInsertIgnore(enabled bool) IFixturer {
+	this.insertIgnore = enabled
+	this.dropCaches()
+	return this
+}
+
+// insertIgnores reports whether the inserts of the table skip the rows of the existing keys.
+func (this *Fixturer) insertIgnores(table string) bool {
+	return this.insertIgnore || this.tableOptions(table).insertIgnore
+}
+
+// checkInsertIgnore fails if the rows of a table are to be skipped while the dialect can't do it,
+// so the table is not left uncleared and inserted with plain inserts failing on the duplicates.
+func (this *Fixturer) checkInsertIgnore(queries []*insertQuery) error {
+	if _, ok := this.dialect.(InsertIgnoreDialect); ok {
+		return nil
+	}
+	for _, query := range queries {
+		if this.insertIgnores(query.table) && !this.upserts(query.table) {
+			return fmt.Errorf("dialect %T does not support insert ignore of table %s", this.dialect, query.table)
+		}
+	}
+	return nil
+}
+
+// LastImportSummary returns the count of tables and rows loaded by the most recent successful import.
+// It returns zeros if the import failed.
+func (this *Fixturer) LastImportSummary() (tables int, rows int) {
+	return len(this.lastImportTables), this.lastImportRows
+}
+
+// LoadedTables returns the sorted names of the tables truncated and populated by the most recent successful import.
+func (this *Fixturer) LoadedTables() []string {
+	return append([]string(nil), this.lastImportTables...)
+}
+
+// EmptyTables returns the sorted names of the tables whose fixture files had no rows in the most recent
+// successful import, which usually means somebody forgot to fill the fixture.
+func (this *Fixturer) EmptyTables() []string {
+	return append([]string(nil), this.lastEmptyTables...)
+}
+
+// DatabaseRecreated reports whether the most recent RecreateDatabaseWithSchemaAndImportFixtures recreated
+// the database, as opposed to importing into the existing one kept by SetRecreateDatabase(false),
+// SetRecreateIfAbsent or RecreateEnv, or into the database of the caller.
+func (this *Fixturer) DatabaseRecreated() bool {
+	return this.recreated
+}
+
+// setLastImport remembers the result of the successful import.
+func (this *Fixturer) setLastImport(tables []string, rows int) {
+	this.lastImportTables = append([]string(nil), tables...)
+	sort.Strings(this.lastImportTables)
+	this.lastImportRows = rows
+
+	this.lastEmptyTables = nil
+	for _, query := range this.queriesInOrder(tables, true) {
+		if query.empty() {
+			this.lastEmptyTables = append(this.lastEmptyTables, query.table)
+		}
+	}
+	sort.Strings(this.lastEmptyTables)
+}
+
+// WithTxPerTable makes every table to be cleared and inserted in its own committed transaction instead of
+// all the tables sharing the worker transactions, which is the default. The atomicity is per table then:
+// a failure of one table does not roll back the others, and ImportFixtures returns *TablesImportError
+// listing the succeeded and failed tables. The tables are cleared with DELETE inside their transactions
+// unless the foreign key checks are kept on, in which case they are cleared child tables first beforehand.
+func (this *Fixturer) WithTxPerTable(enabled bool) IFixturer {
+	this.txPerTable = enabled
+	return this
+}
+
+// SetDisableForeignKeyChecks controls whether the schema load and the import run with FOREIGN_KEY_CHECKS=0.
+// Default is true. Without disabling the checks the tables are inserted with parent tables first
+// and cleared with DELETE child tables first, so the fixtures violating foreign keys fail the import.
+// The rows of a table referencing itself are inserted parents first (see SelfReferencesDialect).
+func (this *Fixturer) SetDisableForeignKeyChecks(disable bool) IFixturer {
+	this.disableForeignKeys = disable
+	this.disableSchemaFks = disable
+	this.dropCaches()
+	return this
+}
+
+// WithEnforceForeignKeys keeps the foreign key checks on during the import only, so a fixture referencing
+// a nonexistent parent row fails the import. See SetDisableForeignKeyChecks for the load order.
+func (this *Fixturer) WithEnforceForeignKeys(enforce bool) IFixturer {
+	this.disableForeignKeys = !enforce
+	this.dropCaches()
+	return this
+}
+
+// SetDisableSchemaForeignKeyChecks controls whether the schema load runs with FOREIGN_KEY_CHECKS=0,
+// e.g. for the schemas creating tables before the tables they reference. Default is true.
+func (this *Fixturer) SetDisableSchemaForeignKeyChecks(disable bool) IFixturer {
+	this.disableSchemaFks = disable
+	return this
+}
+
+// WithProgress sets the callback receiving the import progress events. The callback is never called concurrently.
+func (this *Fixturer) WithProgress(callback func(ProgressEvent)) IFixturer {
+	this.progress.callback = callback
+	return this
+}
+
+// UseDatabase switches the fixturer to another database, e.g. to load the same fixtures for several tenants.
+// The open connection is closed, so the next operation connects to the new database.
+func (this *Fixturer) UseDatabase(name string) IFixturer {
+	if this.db != nil {
+		this.ensureDbDisconnected()
+	}
+	this.dbName = name
+	return this
+}
+
+// WithStreaming makes the fixture files of at least StreamingFileSizeThreshold bytes to be decoded and inserted
+// in batches at load time instead of being read into memory as a whole. Each YAML document of a streamed file
+// is either a row or a list of rows, so splitting a huge fixture into documents bounds the memory used.
+// The streamed files are not cached and are read again on every import. Only the .yml fixtures are streamed.
+// Their rows are repeated and deduped row by row like the others, but a streamed file can't be a multi-table
+// fixture, a template (see WithTemplates) or have deferred columns (see WithDeferredColumns), it fails the import.
+func (this *Fixturer) WithStreaming(enabled bool) IFixturer {
+	this.streaming = enabled
+	this.dropCaches()
+	return this
+}
+
+// WithMaxRowsPerTable limits the rows loaded from every fixture file to the first n, e.g. for smoke tests.
+// Zero or a negative value means no limit.
+func (this *Fixturer) WithMaxRowsPerTable(n int) IFixturer {
+	this.maxRowsPerTable = n
+	this.dropCaches()
+	return this
+}
+
+// WithBatchSize splits the inserts of every table into the statements of at most n rows, e.g. to stay below
+// max_allowed_packet of MySQL. The batch_size of a table in the manifest takes precedence. Zero, the default,
+// inserts all the rows of a table with a single statement, except the streamed ones (see StreamingBatchSize).
+func (this *Fixturer) WithBatchSize(n int) IFixturer {
+	if n < 0 {
+		panic("Batch size must be >= 0.")
+	}
+	this.defaultBatchSize = n
+	this.dropCaches()
+	return this
+}
+
+// batchSize returns the rows count of the inserts of the table, zero if not limited.
+func (this *Fixturer) batchSize(table string) int {
+	if size := this.tableOptions(table).batchSize; size > 0 {
+		return size
+	}
+	return this.defaultBatchSize
+}
+
+// SetRecreateDatabase controls whether RecreateDatabaseWithSchemaAndImportFixtures recreates the database and
+// loads the schema before the import. Default is true. The package registers no command line flag for it,
+// a program wanting one may pass its value here. The RecreateEnv variable overrides it.
+func (this *Fixturer) SetRecreateDatabase(recreate bool) IFixturer {
+	this.recreateDatabase = recreate
+	return this
+}
+
+// SetRecreateIfAbsent makes RecreateDatabaseWithSchemaAndImportFixtures to create the database and load the schema
+// only if the database does not exist yet, otherwise just the fixtures are imported.
+// It takes precedence over SetRecreateDatabase.
+func (this *Fixturer) SetRecreateIfAbsent(enabled bool) IFixturer {
+	this.recreateIfAbsent = enabled
+	return this
+}
+
+// SetRowTransformer sets the function applied to every fixture row before the insert is built.
+func (this *Fixturer) SetRowTransformer(transformer RowTransformer) IFixturer {
+	this.rowTransformer = transformer
+	this.dropCaches()
+	return this
+}
+
+// SetNoTruncateTables sets the tables which are never cleared before the insert, e.g. the lookup tables
+// seeded by the schema. Their fixtures, if any, are inserted into the existing rows.
+func (this *Fixturer) SetNoTruncateTables(tables []string) IFixturer {
+	this.noTruncateTables = make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		this.noTruncateTables[table] = struct{}{}
+	}
+	return this
+}
+
+// WithStrictYAML makes a malformed fixture fail the import instead of being logged and loaded as empty.
+// In the strict mode a fixture must be a list of rows with scalar values, or a map of tables to such lists.
+func (this *Fixturer) WithStrictYAML(strict bool) IFixturer {
+	this.strictYAML = strict
+	this.dropCaches()
+	return this
+}
+
+// WithValueConverter sets the function applied to every value of the fixture rows after the row transformer,
+// e.g. to store the "12.50" amounts as 1250 cents or the enum labels as their codes.
+func (this *Fixturer) WithValueConverter(converter ValueConverter) IFixturer {
+	this.valueConverter = converter
+	this.dropCaches()
+	return this
+}
+
+// WithSQLMode sets the session sql_mode of the fixturer connections, e.g. "STRICT_TRANS_TABLES",
+// so the imports behave the same regardless of the server defaults. It is ignored by the other databases.
+func (this *Fixturer) WithSQLMode(mode string) IFixturer {
+	this.sqlMode = mode
+	return this
+}
+
+// WithAllowMissingFixturesDir makes ImportFixtures a no-op, without even connecting to the database,
+// when the fixtures directory does not exist. Other errors, e.g. a permission denied, still fail the import.
+func (this *Fixturer) WithAllowMissingFixturesDir(allow bool) IFixturer {
+	this.allowMissingDir = allow
+	return this
+}
+
+// WithKeepDatabaseOnFailure preserves the database after a failed import for inspection: the failure is recorded
+// in the KeptDatabaseMarkerTable table, and RecreateDatabase refuses to drop the database while the table exists.
+// Drop the marker table (or the database) to get the database recreated again.
+func (this *Fixturer) WithKeepDatabaseOnFailure(keep bool) IFixturer {
+	this.keepOnFailure = keep
+	return this
+}
+
+// WithStatementTimeout bounds the time a statement of the import may wait for locks, so an insert stuck
+// behind a conflicting lock of another session aborts instead of hanging. The timeout is set for the session
+// of every import transaction, on MySQL with innodb_lock_wait_timeout and lock_wait_timeout (whole seconds,
+// at least one) and max_execution_time, which MySQL applies to SELECT statements only,
+// on PostgreSQL with statement_timeout and lock_timeout.
+func (this *Fixturer) WithStatementTimeout(timeout time.Duration) IFixturer {
+	this.statementTimeout = timeout
+	return this
+}
+
+// statementTimeoutQueries returns the statements setting the statement timeout for the session.
+func (this *Fixturer) statementTimeoutQueries() []string {
+	if this.statementTimeout <= 0 {
+		return nil
+	}
+	return this.dialect.StatementTimeout(this.statementTimeout)
+}
+
+// RecreateDatabaseWithSchemaAndImportFixtures opens a single server connection for the database check and
+// the recreation, and a single database connection shared by the schema load and the import, closed at the end.
 func (this *Fixturer) RecreateDatabaseWithSchemaAndImportFixtures() error {
+	return this.RecreateDatabaseWithSchemaAndImportFixturesContext(context.Background())
+}
+
+func (this *Fixturer) recreateDatabaseWithSchemaAndImportFixtures() error {
+	this.recreated = false
+	if this.externalDb {
+		return this.ImportFixtures()
+	}
+	server, err := this.openServerDb()
+	if err != nil {
+		return err
+	}
+	defer server.Close()
 
-	if this.recreateDatabase == true {
-		if err := this.RecreateDatabase(); err != nil {
+	recreate := this.recreateDatabase
+	if this.recreateIfAbsent {
+		exists, err := this.databaseExistsOn(server)
+		if err != nil {
+			return err
+		}
+		recreate = !exists
+	}
+	if override, find, err := recreateFromEnv(); err != nil {
+		return err
+	} else if find {
+		recreate = override
+	}
+
+	if recreate {
+		if err := this.recreateDatabaseOn(server); err != nil {
 			return err
 		}
+		this.recreated = true
+		// LoadDbSchema leaves the connection open for ImportFixtures, which closes it.
 		if err := this.LoadDbSchema(); err != nil {
+			if this.db != nil {
+				this.ensureDbDisconnected()
+			}
 			return err
 		}
 	}
@@ -91,7 +681,23 @@ func (this *Fixturer) RecreateDatabaseWithSchemaAndImportFixtures() error {
 
 // InitFixtures load and import test fixtures to test database
 func (this *Fixturer) ImportFixtures() error {
-	files, err := this.getYmlFilesList(this.fixturesPathYml)
+	return this.ImportFixturesContext(context.Background())
+}
+
+func (this *Fixturer) importFixtures() error {
+	if this.allowMissingDir && this.fixturesGlob == "" && this.fixturesArchive == "" && this.fixturesFS == nil &&
+		this.fixtureSource == nil {
+		if _, err := os.Stat(this.fixturesPathYml); os.IsNotExist(err) {
+			this.logger.Log(LogEvent{
+				Level:   LevelInfo,
+				Message: fmt.Sprintf("Fixtures directory %s does not exist, nothing to import", this.fixturesPathYml),
+				File:    this.fixturesPathYml,
+			})
+			return nil
+		}
+	}
+
+	files, err := this.fixtureFiles()
 	if err != nil {
 		return err
 	}
@@ -101,196 +707,835 @@ func (this *Fixturer) ImportFixtures() error {
 	}
 	defer this.ensureDbDisconnected()
 
+	if this.preImportSQLFile != "" {
+		if err := this.execLoggedSqlFile(PhasePreImport, this.preImportSQLFile); err != nil {
+			return err
+		}
+	}
+
 	if err := this.importYmlFixtures(files); err != nil {
+		if this.keepOnFailure {
+			this.markDatabaseKept(err)
+		}
 		return err
 	}
 
+	if err := this.execSqlFixtures(this.db); err != nil {
+		return err
+	}
+
+	if this.postImportSQLFile != "" {
+		if err := this.execLoggedSqlFile(PhasePostImport, this.postImportSQLFile); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// ImportFixturesFrom imports the fixtures of the directory instead of the configured fixtures directory,
+// glob pattern, archive, file system or source, which are used again by the next calls. The parsed fixtures are cached
+// per directory.
+func (this *Fixturer) ImportFixturesFrom(dir string) error {
+	path, glob, archive := this.fixturesPathYml, this.fixturesGlob, this.fixturesArchive
+	fsys, source := this.fixturesFS, this.fixtureSource
+	defer func() {
+		this.fixturesPathYml, this.fixturesGlob, this.fixturesArchive = path, glob, archive
+		this.fixturesFS, this.fixtureSource = fsys, source
+	}()
+
+	this.fixturesPathYml, this.fixturesGlob, this.fixturesArchive = dir, "", ""
+	this.fixturesFS, this.fixtureSource = nil, nil
+	return this.ImportFixtures()
+}
+
+// Reset truncates the tables and inserts the fixtures parsed by the prior ImportFixtures again without reading
+// any files (except the streamed and the .sql ones) or checking the schema. Unlike ImportFixtures it keeps
+// the connection open for the next Reset, so it is cheap enough to be called in a benchmark loop.
+// Call Close to release the connection.
+func (this *Fixturer) Reset() error {
+	if !this.cache().parsed {
+		return fmt.Errorf("fixtures of %s are not imported yet, call ImportFixtures before Reset", this.fixturesSource())
+	}
+
+	if err := this.ensureDbConnected(); err != nil {
+		return err
+	}
+	if err := this.loadParsedData(); err != nil {
+		return err
+	}
+	return this.execSqlFixtures(this.db)
+}
+
+// BuildSQL returns the insert statement and its arguments built for the table without executing anything.
+// The fixtures are parsed unless they are cached already; without a connection the generated columns
+// are not stripped and the parse is not cached for the imports. A table split into several batches
+// gets its statements joined by semicolons.
+func (this *Fixturer) BuildSQL(table string) (query string, args []interface{}, err error) {
+	files, err := this.fixtureFiles()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := this.parseFixtures(files); err != nil {
+		return "", nil, err
+	}
+
+	queries := this.queriesInOrder([]string{table}, true)
+	if len(queries) == 0 {
+		return "", nil, fmt.Errorf("no fixture for table %s in %s", table, this.fixturesSource())
+	}
+	if queries[0].streamed {
+		return "", nil, fmt.Errorf("fixture %s is streamed, its statements are built at load time", queries[0].file)
+	}
+	if queries[0].empty() {
+		return "", nil, fmt.Errorf("fixture %s has no rows", queries[0].file)
+	}
+
+	var statements []string
+	for _, qb := range queries[0].qbs {
+		statement, statementArgs, err := qb.ToSql()
+		if err != nil {
+			return "", nil, err
+		}
+		statements = append(statements, statement)
+		args = append(args, statementArgs...)
+	}
+	return strings.Join(statements, ";\n"), args, nil
+}
+
+// Close releases the connection kept open by Reset or LoadDbSchema.
+func (this *Fixturer) Close() error {
+	if this.db == nil || this.externalDb {
+		return nil
+	}
+	err := this.db.Close()
+	this.db = nil
+	return err
+}
+
 // RecreateDatabase drops existing database and creates a clean one.
 func (this *Fixturer) RecreateDatabase() error {
+	return this.RecreateDatabaseContext(context.Background())
+}
 
+func (this *Fixturer) recreateServerDatabase() error {
 	// this.db is not used because this.db must be connected to the existing database that might not exists at the moment.
-	db, err := sql.Open("mysql", this.dbConf)
+	db, err := this.openServerDb()
 
 	if err != nil {
 		return err
 	}
-	log.Printf("Drop database %s", this.dbName)
-	if _, err := db.Exec("DROP DATABASE IF EXISTS " + this.dbName); err != nil {
-		return err
+	defer db.Close()
+
+	return this.recreateDatabaseOn(db)
+}
+
+// recreateDatabaseOn drops and creates the database with the server connection.
+func (this *Fixturer) recreateDatabaseOn(db *sql.DB) error {
+	// The sessions of the open connection would keep the dropped database selected.
+	if this.db != nil {
+		this.ensureDbDisconnected()
 	}
-	log.Printf("Create database %s", this.dbName)
-	if _, err := db.Exec("CREATE DATABASE " + this.dbName); err != nil {
-		return err
+
+	if this.keepOnFailure {
+		if err := this.checkDatabaseNotKept(db); err != nil {
+			return err
+		}
+	}
+
+	this.logger.Log(LogEvent{Level: LevelInfo, Message: "Recreate database " + this.dbName, Phase: PhaseRecreating})
+	for _, step := range []struct {
+		message string
+		run     func(ctx context.Context, server *sql.DB, dbConf, name string) error
+	}{
+		{"Drop database", this.dialect.DropDatabase},
+		{"Create database", this.dialect.CreateDatabase},
+	} {
+		start := time.Now()
+		if err := step.run(this.context(), db, this.dbConf, this.dbName); err != nil {
+			return err
+		}
+		this.logger.Log(LogEvent{
+			Level: LevelDebug, Message: step.message + " " + this.dbName + " done", Phase: PhaseRecreating, Duration: time.Since(start),
+		})
 	}
-	db.Close()
 
 	return nil
 }
 
-// The return value of the function is intentionally left []os.FileInfo (but not []string)
+// DropDatabase closes the open connection, if any, and drops the database.
+func (this *Fixturer) DropDatabase() error {
+	return this.DropDatabaseContext(context.Background())
+}
+
+func (this *Fixturer) dropDatabase() error {
+	if this.db != nil {
+		this.ensureDbDisconnected()
+	}
+
+	db, err := this.openServerDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	this.logger.Log(LogEvent{Level: LevelInfo, Message: "Drop database " + this.dbName, Phase: PhaseRecreating})
+	return this.dialect.DropDatabase(this.context(), db, this.dbConf, this.dbName)
+}
+
+// databaseExistsOn checks whether the database exists with the server connection.
+func (this *Fixturer) databaseExistsOn(db *sql.DB) (bool, error) {
+	return this.dialect.DatabaseExists(db, this.dbConf, this.dbName)
+}
+
+// fixtureFiles returns the fixture files to load from the fixture source, the file system, the archive,
+// the glob pattern or the fixtures directory.
+func (this *Fixturer) fixtureFiles() ([]fixtureFile, error) {
+	var files []fixtureFile
+	var err error
+	if this.fixtureSource != nil {
+		files, err = this.fetchSourceFiles()
+	} else if this.fixturesFS != nil {
+		files, err = this.fsFiles(this.fixturesFS, this.fixturesFSDir)
+	} else if this.fixturesArchive != "" {
+		files, err = archiveFiles(this.fixturesArchive)
+	} else if this.fixturesGlob != "" {
+		files, err = globFiles(this.fixturesGlob)
+	} else {
+		files, err = this.getYmlFilesList(this.fixturesPathYml)
+		if err == nil {
+			files, err = this.applyManifest(this.fixturesPathYml, files)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDuplicateTables(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// The return value of the function intentionally keeps os.FileInfo (but not just a path string)
 // for the case when more file info needed.
-func (this *Fixturer) getYmlFilesList(path string) ([]os.FileInfo, error) {
+func (this *Fixturer) getYmlFilesList(path string) ([]fixtureFile, error) {
+	if this.recursiveFixtures {
+		return this.walkFixtureFiles(path)
+	}
 
-	files, err := ioutil.ReadDir(this.fixturesPathYml)
+	files, err := ioutil.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var resultSlice []os.FileInfo
+	var resultSlice []fixtureFile
 	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yml") {
+		if file.IsDir() || fixtureExt(file.Name()) == "" || file.Name() == ManifestFileName {
 			continue
 		}
 
-		resultSlice = append(resultSlice, file)
+		resultSlice = append(resultSlice, fixtureFile{FileInfo: file, path: filepath.Join(path, file.Name())})
 	}
 
 	return resultSlice, nil
 }
 
-func (this *Fixturer) importYmlFixtures(files []os.FileInfo) error {
+func (this *Fixturer) importYmlFixtures(files []fixtureFile) error {
 	// The caller of the function must ensureDbConnected() and ensureDbDisconnected() afterwards.
 
-	log.Println("Import YML fixtures")
-	var mutex = &sync.Mutex{}
-
-	mutex.Lock()
-	if _, find := finishedParsedDirs[this.fixturesPathYml]; find {
-		this.loadParsedData()
-		mutex.Unlock()
-		return nil
+	this.logger.Log(LogEvent{Level: LevelInfo, Message: "Import YML fixtures", File: this.fixturesSource()})
+	start := time.Now()
+	if err := this.parseFixtures(files); err != nil {
+		return err
 	}
 
-	mutex.Unlock()
-
-	this.pushInsertQueriesFromYmlToChannel(files)
+	if err := this.checkTablesExist(); err != nil {
+		return err
+	}
 
-	finishedParsedDirs[this.fixturesPathYml] = struct{}{}
+	if err := this.loadParsedData(); err != nil {
+		return err
+	}
+	this.logger.Log(LogEvent{
+		Level:    LevelInfo,
+		Message:  fmt.Sprintf("Imported %d rows into %d tables", this.lastImportRows, len(this.lastImportTables)),
+		File:     this.fixturesSource(),
+		Duration: time.Since(start),
+		Rows:     this.lastImportRows,
+	})
+	return nil
+}
 
-	return this.loadParsedData()
+// parseFixtures parses the fixture files unless the fixtures source is parsed already.
+func (this *Fixturer) parseFixtures(files []fixtureFile) error {
+	cache := this.cache()
+	if cache.parsed {
+		return nil
+	}
+	if err := this.pushInsertQueriesFromYmlToChannel(files); err != nil {
+		return err
+	}
+	// Without the introspection the generated columns are kept and the numeric text columns are floats,
+	// so such a parse, e.g. of BuildSQL, is redone by the next import.
+	cache.parsed = this.introspector() != nil
+	return nil
 }
 
 func (this *Fixturer) loadParsedData() error {
+	this.lastImportTables, this.lastImportRows, this.lastEmptyTables = nil, 0, nil
 
-	if _, err := this.db.Exec("SET FOREIGN_KEY_CHECKS=0"); err != nil {
+	_, levels, err := this.tablesOrder(this.db)
+	if err != nil {
 		return err
 	}
-	defer this.db.Exec("SET FOREIGN_KEY_CHECKS=1")
+	return this.loadTables(levels)
+}
 
-	for _, tableName := range finishedTablseNames {
-		_, err := this.db.Exec("TRUNCATE " + tableName)
-		if err != nil {
-			fmt.Println(err)
+// tablesOrder returns the parsed tables in the load order and their levels, the groups of consecutive tables
+// inserted in any order. The tables referencing each other by foreign keys are in the different levels,
+// and every table is a level of its own when the order of the manifest or a multi-table fixture matters.
+func (this *Fixturer) tablesOrder(q querier) (tables []string, levels [][]string, err error) {
+	tables = this.cache().tables
+	ordered := false
+	if this.manifest != nil {
+		tables, ordered = this.manifest.sortTables(tables), true
+	}
+	for _, query := range this.cache().queries {
+		ordered = ordered || query.multiTable
+	}
+
+	levels = [][]string{tables}
+	if !this.disableForeignKeys {
+		if levels, err = this.levelTablesByForeignKeys(q, tables); err != nil {
+			return nil, nil, err
+		}
+		tables = flattenLevels(levels)
+	}
+	if ordered {
+		levels = make([][]string, len(tables))
+		for i, table := range tables {
+			levels[i] = []string{table}
+		}
+	}
+	return tables, levels, nil
+}
+
+// loadTables clears the tables of the levels and inserts their parsed fixtures level by level.
+func (this *Fixturer) loadTables(levels [][]string) error {
+	var clearBefore, clearInTx []string
+	if !this.insertIgnore && !this.upsert {
+		for _, table := range flattenLevels(levels) {
+			switch strategy := this.tableClearStrategy(table); {
+			case strategy == ClearRecreate:
+				clearBefore = append(clearBefore, table)
+			case this.clearsInTableTx():
+			case strategy == ClearDelete && !this.txPerTable:
+				clearInTx = append(clearInTx, table)
+			default:
+				clearBefore = append(clearBefore, table)
+			}
+		}
+	}
+	if len(clearBefore) > 0 {
+		if err := this.clearTables(clearBefore); err != nil {
 			return err
 		}
 	}
 
-	tx, err := this.db.Begin()
+	queries := make([][]*insertQuery, len(levels))
+	for i, level := range levels {
+		queries[i] = this.queriesInOrder(level, this.clearsInTableTx())
+	}
+	return this.insertParsedData(queries, clearInTx)
+}
+
+// clearTables clears the tables as their clear strategies say, child tables first. TRUNCATE of a table
+// referenced by a foreign key is not permitted while the checks are on, so DELETE is used then.
+func (this *Fixturer) clearTables(tables []string) error {
+	// FOREIGN_KEY_CHECKS is a session variable, so the truncation must run on the same connection.
+	ctx := this.context()
+	conn, err := this.db.Conn(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	defer conn.Close()
 
-	for _, query := range insertMap {
-		queryString, queryValues, err := query.ToSql()
+	if this.disableForeignKeys {
+		if _, err := conn.ExecContext(ctx, this.dialect.ForeignKeyChecks(false)); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, this.dialect.ForeignKeyChecks(true))
+	}
+	for _, query := range this.statementTimeoutQueries() {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
 
+	this.progress.start(PhaseTruncating, len(tables))
+	for i := len(tables) - 1; i >= 0; i-- {
+		this.progress.step(tables[i], 0)
+		if this.skipsClear(tables[i]) {
+			continue
+		}
+		strategy := this.tableClearStrategy(tables[i])
+		query := this.dialect.Truncate(this.quoteTable(tables[i]))
+		if strategy == ClearDelete || !this.disableForeignKeys {
+			query = "DELETE FROM " + this.quoteTable(tables[i])
+		}
+		start := time.Now()
+		var err error
+		if strategy == ClearRecreate {
+			err = this.recreateTable(ctx, conn, tables[i])
+		} else {
+			_, err = conn.ExecContext(ctx, query)
+		}
 		if err != nil {
-			fmt.Println(err)
+			return &FixtureError{Phase: PhaseTruncating, Table: tables[i], Err: err}
 		}
+		this.logger.Log(LogEvent{
+			Level: LevelDebug, Message: "Table cleared", Phase: PhaseTruncating, Table: tables[i], Duration: time.Since(start),
+		})
+	}
 
-		if _, err := tx.Exec(queryString, queryValues...); err != nil {
-			fmt.Println(err)
-		}
+	return nil
+}
+
+// ClearStrategy is the way the tables are cleared before the insert.
+type ClearStrategy int
+
+const (
+	// ClearTruncate truncates the tables before the insert transactions. It is fast, but a failure
+	// of the truncation or the insert leaves the tables cleared so far empty.
+	ClearTruncate ClearStrategy = iota
+	// ClearDelete deletes the rows in the same transaction as the inserts, so a failure rolls everything back
+	// and the tables keep their rows. The tables are inserted by a single worker then.
+	ClearDelete
+	// ClearRecreate drops the tables and creates them again before the insert transactions, e.g. to reset
+	// everything about them after a test altered them. It needs a RecreateTableDialect and, since the tables
+	// referenced by foreign keys can't be dropped, the foreign key checks disabled.
+	ClearRecreate
+)
+
+// WithoutTransaction makes the inserts run in the autocommit mode instead of the worker transactions,
+// trading the atomicity of the import for less lock contention and memory of the server.
+// The import still stops on the first error, but the rows inserted before it stay.
+func (this *Fixturer) WithoutTransaction(enabled bool) IFixturer {
+	this.withoutTransaction = enabled
+	return this
+}
+
+// WithClearStrategy sets the way the tables are cleared, default is ClearTruncate.
+// The per-table transactions (see WithTxPerTable) use their own clearing, except for ClearRecreate.
+func (this *Fixturer) WithClearStrategy(strategy ClearStrategy) IFixturer {
+	this.clearStrategy = strategy
+	return this
+}
+
+// clearsInTableTx reports whether every table is cleared inside its own transaction right before the insert.
+func (this *Fixturer) clearsInTableTx() bool {
+	return this.txPerTable && this.disableForeignKeys
+}
+
+func (this *Fixturer) skipsClear(table string) bool {
+	options := this.tableOptions(table)
+	_, noTruncate := this.noTruncateTables[table]
+	return this.insertIgnores(table) || this.upserts(table) || options.skipTruncate || noTruncate
+}
+
+// queriesInOrder returns the parsed inserts ordered as the tables.
+// The fixtures without rows are returned only if includeEmpty is set.
+func (this *Fixturer) queriesInOrder(tables []string, includeEmpty bool) []*insertQuery {
+	cached := this.cache().queries
+	tablesQueries := make(map[string][]*insertQuery, len(cached))
+	for _, query := range cached {
+		tablesQueries[query.table] = append(tablesQueries[query.table], query)
 	}
-	if err := tx.Commit(); err != nil {
-		fmt.Println(err)
-		return err
+
+	queries := make([]*insertQuery, 0, len(cached))
+	for _, table := range tables {
+		for _, query := range tablesQueries[table] {
+			if includeEmpty || !query.empty() {
+				queries = append(queries, query)
+			}
+		}
+		delete(tablesQueries, table)
 	}
+	return queries
+}
 
-	return nil
+// fixturesSource returns the fixture source, the file system, the archive, the glob pattern or the directory
+// the fixtures are loaded from.
+func (this *Fixturer) fixturesSource() string {
+	if this.fixtureSource != nil {
+		return this.fixtureSource.Name()
+	}
+	if this.fixturesFS != nil {
+		return fmt.Sprintf("%T:%s", this.fixturesFS, this.fixturesFSDir)
+	}
+	if this.fixturesArchive != "" {
+		return this.fixturesArchive
+	}
+	if this.fixturesGlob != "" {
+		return this.fixturesGlob
+	}
+	return this.fixturesPathYml
 }
 
-func (this *Fixturer) pushInsertQueriesFromYmlToChannel(files []os.FileInfo) {
+func (this *Fixturer) pushInsertQueriesFromYmlToChannel(files []fixtureFile) error {
 	var wg sync.WaitGroup
 	wg.Add(len(files))
 
+	if err := this.discoverNumericTextColumns(); err != nil {
+		return err
+	}
+
 	tablesNames := []string{}
+	queries := map[string]*insertQuery{}
+	parsed := []*insertQuery{}
+	var firstErr error
 	var mutex = &sync.Mutex{}
+	this.progress.start(PhaseParsing, len(files))
 
 	for _, f := range files {
-		go func(f os.FileInfo) {
+		go func(f fixtureFile) {
 			defer wg.Done()
 
+			start := time.Now()
 			filename := f.Name()
-			if strings.HasSuffix(filename, ".yml") == false {
+			if fixtureExt(filename) == "" {
 				return
 			}
-			data := make([]map[string]interface{}, 0, 10)
-
-			y, _ := ioutil.ReadFile(this.fixturesPathYml + "/" + filename)
-
-			if err := yaml.Unmarshal(y, &data); err != nil {
-				log.Printf("Cant't read fixture %q. Origin error: %v", filename, err)
+			if this.streaming && f.data == nil && fixtureFormat(f.path) == ymlFixtureExt && f.Size() >= StreamingFileSizeThreshold {
+				err := this.checkStreamable(f.tableName())
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = &FixtureError{Phase: PhaseParsing, Table: f.tableName(), File: f.path, Err: err}
+				}
+				tablesNames = append(tablesNames, f.tableName())
+				queries[f.path] = &insertQuery{file: f.path, table: f.tableName(), streamed: true}
+				mutex.Unlock()
+				this.progress.step(f.tableName(), 0)
+				return
 			}
 
-			tableName := strings.TrimSuffix(filename, ".yml")
-			mutex.Lock()
-			tablesNames = append(tablesNames, tableName)
-			mutex.Unlock()
+			tables, err := this.parseFixtureTables(f)
+			if err != nil {
+				mutex.Lock()
+				if firstErr == nil {
+					firstErr = &FixtureError{Phase: PhaseParsing, Table: f.tableName(), File: f.path, Err: err}
+				}
+				mutex.Unlock()
+				return
+			}
 
-			allKeysMap := map[string]struct{}{}
-			for _, item := range data {
-				for k := range item {
-					allKeysMap[k] = struct{}{}
+			var stepTables []string
+			var stepRows int
+			for _, t := range tables {
+				query := &insertQuery{file: f.path, table: t.table, rows: t.rows, multiTable: t.multiTable}
+				key := f.path
+				if t.multiTable {
+					key += "#" + t.table
 				}
+				mutex.Lock()
+				tablesNames = append(tablesNames, t.table)
+				queries[key] = query
+				parsed = append(parsed, query)
+				mutex.Unlock()
+				stepTables, stepRows = append(stepTables, t.table), stepRows+len(t.rows)
+
+				this.logger.Log(LogEvent{
+					Level:    LevelDebug,
+					Message:  "Fixture parsed",
+					Phase:    PhaseParsing,
+					Table:    t.table,
+					File:     f.path,
+					Duration: time.Since(start),
+					Rows:     len(t.rows),
+				})
 			}
+			this.progress.step(strings.Join(stepTables, ","), stepRows)
+
+			return
+		}(f)
+	}
 
-			allKeys := make([]string, 0, len(allKeysMap))
+	wg.Wait()
 
-			for k := range allKeysMap {
-				allKeys = append(allKeys, k)
-			}
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := checkDuplicateQueries(parsed); err != nil {
+		return err
+	}
+	this.labels = map[string]labeledRow{}
+	if err := this.buildParsedInserts(parsed); err != nil {
+		return err
+	}
+
+	cache := this.cache()
+	cache.tables, cache.queries = tablesNames, queries
+	return nil
+}
+
+// buildParsedInserts resolves the references between the parsed fixtures, transforms the rows
+// and builds the inserts.
+func (this *Fixturer) buildParsedInserts(queries []*insertQuery) error {
+	if err := this.checkUpsert(queries); err != nil {
+		return err
+	}
+	if err := this.checkInsertIgnore(queries); err != nil {
+		return err
+	}
+	if err := this.registerLabels(queries); err != nil {
+		return err
+	}
+
+	if err := this.discoverGeneratedColumns(); err != nil {
+		return err
+	}
+	if err := this.discoverIdentityColumns(); err != nil {
+		return err
+	}
+	if err := this.discoverSelfReferences(); err != nil {
+		return err
+	}
 
-			qb := squirrel.Insert(tableName).Columns(allKeys...)
+	var primaryKeys map[string][]string
+	if this.dedupeByPrimaryKey {
+		var err error
+		if primaryKeys, err = this.primaryKeys(); err != nil {
+			return err
+		}
+	}
 
-			for _, item := range data {
-				qb.AddMap(item)
+	for _, query := range queries {
+		for i := range query.rows {
+			err := this.expandEnv(query.rows[i])
+			if err == nil {
+				err = this.resolveReferences(query.rows[i])
 			}
+			if err == nil {
+				query.rows[i], err = this.transformRow(query.table, query.rows[i])
+			}
+			if err != nil {
+				return queryError(PhaseParsing, query, fmt.Errorf("row %d: %w", i, err))
+			}
+			this.stripIgnoredColumns(query.table, query.rows[i])
+			this.applyDefaults(query.rows[i])
+			if err := encodeJSONValues(query.rows[i]); err != nil {
+				return queryError(PhaseParsing, query, fmt.Errorf("row %d: %w", i, err))
+			}
+		}
+		if this.dedupeByPrimaryKey {
+			if err := this.dedupeRows(query, primaryKeys); err != nil {
+				return err
+			}
+		}
+		this.sortRowsBySelfReferences(query)
+		rows, err := this.deferColumns(query)
+		if err != nil {
+			return queryError(PhaseParsing, query, err)
+		}
 
-			mutex.Lock()
-			insertMap[filename] = qb
-			mutex.Unlock()
+		// An empty fixture only truncates the table: an insert without columns is not valid SQL.
+		if len(query.rows) == 0 {
+			continue
+		}
+		query.columns = rowsColumns(query.rows)
+		query.qbs = this.buildInserts(query.table, query.columns, rows)
+	}
+	return nil
+}
 
-			return
-		}(f)
+// parseFixtureTables reads the fixture file and returns the rows of its table,
+// or of each table of a multi-table fixture.
+func (this *Fixturer) parseFixtureTables(f fixtureFile) ([]fixtureTable, error) {
+	y, err := fixtureData(f)
+	if err != nil && this.strictYAML {
+		return nil, err
+	}
+	// An encoding or a template error fails the import even if not strict, since it can't be a fixture anyway.
+	if err == nil {
+		if y, err = fixtureText(f.path, y); err != nil {
+			return nil, err
+		}
+		if y, err = this.executeTemplate(f.path, y); err != nil {
+			return nil, err
+		}
 	}
 
-	wg.Wait()
+	decoder := &fixtureDecoder{path: f.path, strict: this.strictYAML, table: f.tableName(), numericText: this.numericTextColumns}
+	tables, err := decoder.decodeTables(y)
+	if err != nil && this.strictYAML {
+		return nil, err
+	}
+	if err != nil {
+		this.logger.Log(LogEvent{
+			Level:   LevelWarn,
+			Message: fmt.Sprintf("Cant't read fixture %q. Origin error: %v", f.Name(), err),
+			Phase:   PhaseParsing,
+			Table:   f.tableName(),
+			File:    f.path,
+			Err:     err,
+		})
+		tables = []fixtureTable{{}}
+	}
 
-	mutex.Lock()
-	finishedTablseNames = tablesNames
-	mutex.Unlock()
-	return
+	for i := range tables {
+		if tables[i].rows, err = this.repeatRows(f.path, tables[i].rows); err != nil {
+			return nil, err
+		}
+		if tables[i].table == "" {
+			tables[i].table = f.tableName()
+		} else if f.schema != "" && !strings.Contains(tables[i].table, ".") {
+			tables[i].table = f.schema + "." + tables[i].table
+		}
+		if this.maxRowsPerTable > 0 && len(tables[i].rows) > this.maxRowsPerTable {
+			tables[i].rows = tables[i].rows[:this.maxRowsPerTable]
+		}
+	}
+
+	return tables, nil
+}
+
+// transformRow applies the row transformer and the value converter, if any, to the row.
+func (this *Fixturer) transformRow(table string, row map[string]interface{}) (map[string]interface{}, error) {
+	if this.rowTransformer != nil {
+		var err error
+		if row, err = this.rowTransformer(table, row); err != nil {
+			return nil, err
+		}
+	}
+	if this.valueConverter == nil {
+		return row, nil
+	}
+
+	for column, raw := range row {
+		value, err := this.valueConverter(table, column, raw)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		row[column] = value
+	}
+	return row, nil
+}
+
+// rowsColumns returns the union of the rows keys.
+func rowsColumns(rows []map[string]interface{}) []string {
+	allKeysMap := map[string]struct{}{}
+	for _, item := range rows {
+		for k := range item {
+			allKeysMap[k] = struct{}{}
+		}
+	}
+
+	allKeys := make([]string, 0, len(allKeysMap))
+	for k := range allKeysMap {
+		allKeys = append(allKeys, k)
+	}
+	return allKeys
+}
+
+// QuoteMySQLIdentifier quotes the identifier with backticks, so reserved words and special characters
+// may be used in table and column names.
+func QuoteMySQLIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// quoteTable quotes the table name. A dotted name, e.g. from the fixture analytics.events.yml,
+// is a table of another schema and its parts are quoted separately: `analytics`.`events`.
+func (this *Fixturer) quoteTable(table string) string {
+	return quoteQualifiedName(this.quoteIdentifier, table)
+}
+
+func quoteQualifiedName(quote func(string) string, name string) string {
+	if i := strings.Index(name, "."); i > 0 && i < len(name)-1 {
+		return quote(name[:i]) + "." + quote(name[i+1:])
+	}
+	return quote(name)
+}
+
+// buildInserts splits the rows by the batch size of the table and builds an insert for every batch.
+// The rows omitting some of the columns are grouped by their column sets unless WithMissingColumns says otherwise.
+func (this *Fixturer) buildInserts(tableName string, columns []string, rows []map[string]interface{}) []squirrel.InsertBuilder {
+	if this.missingColumns == MissingColumnsGroup && sparseRows(rows, columns) {
+		var qbs []squirrel.InsertBuilder
+		groupsColumns, groups := groupRowsByColumns(rows)
+		for i, group := range groups {
+			qbs = append(qbs, this.buildInserts(tableName, groupsColumns[i], group)...)
+		}
+		return qbs
+	}
+
+	batchSize := this.batchSize(tableName)
+	if batchSize <= 0 {
+		batchSize = len(rows)
+	}
+
+	var qbs []squirrel.InsertBuilder
+	for len(rows) > 0 {
+		n := batchSize
+		if n > len(rows) {
+			n = len(rows)
+		}
+		qbs = append(qbs, this.buildInsert(tableName, columns, rows[:n]))
+		rows = rows[n:]
+	}
+	return qbs
+}
+
+// buildInsert builds the multi-row insert of the rows using the configured placeholder format and identifier quoting.
+func (this *Fixturer) buildInsert(tableName string, columns []string, rows []map[string]interface{}) squirrel.InsertBuilder {
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = this.quoteIdentifier(column)
+	}
+
+	qb := squirrel.Insert(this.quoteTable(tableName)).
+		Columns(quotedColumns...).
+		PlaceholderFormat(this.placeholderFormat)
+	if d, ok := this.dialect.(UpsertDialect); ok && this.upserts(tableName) {
+		keyColumns, updateColumns := this.upsertColumns(tableName, columns)
+		qb = d.Upsert(qb, keyColumns, updateColumns)
+	} else if d, ok := this.dialect.(InsertIgnoreDialect); ok && this.insertIgnores(tableName) {
+		qb = d.IgnoreDuplicates(qb)
+	}
+
+	for _, row := range rows {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			value, find := row[column]
+			if !find {
+				value = this.missingValue()
+			}
+			if _, ok := value.(columnDefault); ok {
+				value = squirrel.Expr("DEFAULT")
+			}
+			values[i] = value
+		}
+		qb = qb.Values(values...)
+	}
+
+	return qb
 }
 
 func (this *Fixturer) ensureDbConnected() error {
 	if this.db != nil {
 		return nil
 	}
-	dsn := this.dbConf + this.dbName
-	if this.dbParams != "" {
-		dsn += "?" + this.dbParams
+	dsn, err := this.databaseDSN()
+	if err != nil {
+		return err
 	}
-	db, err := sql.Open("mysql", dsn)
+	db, err := sql.Open(this.dialect.DriverName(), dsn)
 	if err != nil {
 		return err
 	}
 	db.SetMaxOpenConns(this.insertGoroutinesCnt)
 	db.SetMaxIdleConns(this.insertGoroutinesCnt)
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(this.context()); err != nil {
+		db.Close()
 		return err
 	}
 	this.db = db
@@ -298,43 +1543,126 @@ func (this *Fixturer) ensureDbConnected() error {
 }
 
 func (this *Fixturer) ensureDbDisconnected() {
+	if this.externalDb {
+		return
+	}
 	// Ignore error.
 	_ = this.db.Close()
 	this.db = nil
 }
 
 func (this *Fixturer) LoadDbSchema() error {
-	log.Println("Load database schema")
+	return this.LoadDbSchemaContext(context.Background())
+}
+
+func (this *Fixturer) loadDbSchema() error {
+	this.logger.Log(LogEvent{Level: LevelInfo, Message: "Load database schema", Phase: PhaseSchema, File: this.schema})
+	start := time.Now()
+	defer func() {
+		this.logger.Log(LogEvent{
+			Level: LevelDebug, Message: "Database schema loaded", Phase: PhaseSchema, File: this.schema, Duration: time.Since(start),
+		})
+	}()
 
 	if err := this.ensureDbConnected(); err != nil {
 		return err
 	}
 
-	tx, err := this.db.Begin()
+	ctx := this.context()
+	tx, err := this.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if _, err = tx.Exec("SET FOREIGN_KEY_CHECKS=0"); err != nil {
-		return err
+	if this.disableSchemaFks {
+		if _, err = tx.ExecContext(ctx, this.dialect.ForeignKeyChecks(false)); err != nil {
+			return err
+		}
+		defer tx.Exec(this.dialect.ForeignKeyChecks(true))
 	}
-	defer tx.Exec("SET FOREIGN_KEY_CHECKS=1")
 
-	if file, err := ioutil.ReadFile(this.schema); err == nil {
-		queries := strings.Split(string(file), ";")
+	existing := map[string]struct{}{}
+	if this.ensureSchema {
+		if existing, err = this.existingTables(); err != nil {
+			return err
+		}
+	}
 
-		for i := range queries {
-			query := strings.TrimSpace(queries[i])
-			if len(query) == 0 {
-				continue
+	files, err := this.readSchemaFiles()
+	if err != nil {
+		return err
+	}
+	var applied []string
+	for _, file := range files {
+		for _, query := range splitSqlStatements(file.script) {
+			if target := schemaStatementTarget(query); target != "" {
+				if _, find := existing[target]; find {
+					this.logger.Log(LogEvent{
+						Level:   LevelInfo,
+						Message: fmt.Sprintf("Skip creating %s, it already exists", target),
+						Phase:   PhaseSchema,
+						Table:   target,
+						File:    file.path,
+					})
+					continue
+				}
 			}
-			if _, err := tx.Exec(query); err != nil {
-				return err
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				tx.Rollback()
+				return this.schemaFailure(applied, fmt.Errorf("%s: %w", file.path, err))
+			}
+			if isImplicitCommitStatement(query) {
+				applied = append(applied, query)
 			}
 		}
-		return tx.Commit()
-	} else {
+	}
+	return tx.Commit()
+}
+
+// splitSqlStatements splits the SQL script by semicolons skipping empty statements.
+func splitSqlStatements(script string) []string {
+	var statements []string
+	for _, query := range strings.Split(script, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+		statements = append(statements, query)
+	}
+	return statements
+}
+
+// execLoggedSqlFile executes the pre- or post-import SQL file and logs it.
+func (this *Fixturer) execLoggedSqlFile(phase ProgressPhase, path string) error {
+	this.logger.Log(LogEvent{Level: LevelInfo, Message: fmt.Sprintf("Execute %s SQL file %s", phase, path), Phase: phase, File: path})
+	start := time.Now()
+	if err := this.execSqlFile(path); err != nil {
 		return err
 	}
+	this.logger.Log(LogEvent{Level: LevelDebug, Message: "SQL file executed", Phase: phase, File: path, Duration: time.Since(start)})
+	return nil
+}
+
+// execSqlFile executes the statements of the SQL file one by one on a single connection,
+// so session variables set by the file apply to the following statements.
+func (this *Fixturer) execSqlFile(path string) error {
+	file, err := readFile(path)
+	if err != nil {
+		return err
+	}
+
+	ctx := this.context()
+	conn, err := this.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, query := range splitSqlStatements(string(file)) {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,512 @@
+package fixturer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDriverName is the database/sql driver of the fake databases recording the executed statements.
+const fakeDriverName = "fixturer-fake"
+
+var (
+	fakeDBs   sync.Map
+	fakeDBSeq int64
+)
+
+func init() {
+	sql.Register(fakeDriverName, fakeDriver{})
+}
+
+// fakeDB records the statements executed on its connections, the queries return the rows of the respond
+// function, if any, and nothing otherwise.
+type fakeDB struct {
+	mutex      sync.Mutex
+	statements []fakeStatement
+	respond    func(query string, args []driver.Value) (columns []string, rows [][]driver.Value)
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// newFakeDB registers a fake database forgotten at the end of the test and returns its DSN.
+func newFakeDB(t *testing.T, respond func(query string, args []driver.Value) ([]string, [][]driver.Value)) (string, *fakeDB) {
+	fake := &fakeDB{respond: respond}
+	name := fmt.Sprintf("fake-%d", atomic.AddInt64(&fakeDBSeq, 1))
+	fakeDBs.Store(name, fake)
+	t.Cleanup(func() {
+		fakeDBs.Delete(name)
+	})
+	return name, fake
+}
+
+// openFakeDB opens a fake database closed at the end of the test.
+func openFakeDB(t *testing.T, respond func(query string, args []driver.Value) ([]string, [][]driver.Value)) (*sql.DB, *fakeDB) {
+	t.Helper()
+	name, fake := newFakeDB(t, respond)
+	db, err := sql.Open(fakeDriverName, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	return db, fake
+}
+
+// executed returns the recorded statements starting with the prefix.
+func (this *fakeDB) executed(prefix string) []fakeStatement {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var statements []fakeStatement
+	for _, statement := range this.statements {
+		if strings.HasPrefix(statement.query, prefix) {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, find := fakeDBs.Load(name)
+	if !find {
+		return nil, fmt.Errorf("fake database %s is not open", name)
+	}
+	return &fakeConn{db: fake.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (this *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake database does not prepare statements")
+}
+
+func (this *fakeConn) Close() error {
+	return nil
+}
+
+func (this *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (this *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	this.db.mutex.Lock()
+	defer this.db.mutex.Unlock()
+	this.db.statements = append(this.db.statements, fakeStatement{query: query, args: namedValues(args)})
+	return driver.RowsAffected(1), nil
+}
+
+func (this *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &fakeRows{}
+	if this.db.respond != nil {
+		rows.columns, rows.rows = this.db.respond(query, namedValues(args))
+	}
+	if len(rows.columns) == 0 {
+		rows.columns = []string{"value"}
+	}
+	return rows, nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (this *fakeRows) Columns() []string {
+	return this.columns
+}
+
+func (this *fakeRows) Close() error {
+	return nil
+}
+
+func (this *fakeRows) Next(dest []driver.Value) error {
+	if len(this.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, this.rows[0])
+	this.rows = this.rows[1:]
+	return nil
+}
+
+// respondTables makes the fake MySQL database have the tables of the current database.
+func respondTables(tables ...string) func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	return func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if query != (MySQLDialect{}).TablesQuery() {
+			return nil, nil
+		}
+		rows := make([][]driver.Value, len(tables))
+		for i, table := range tables {
+			rows[i] = []driver.Value{table}
+		}
+		return []string{"TABLE_NAME"}, rows
+	}
+}
+
+// writeFixtures writes the fixture files by their slash separated paths into a temporary directory.
+func writeFixtures(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// newFakeFixturer makes the fixturer of the fake database logging nothing.
+func newFakeFixturer(db *sql.DB, dir string) IFixturer {
+	return NewFixturerWithDB(db, "", dir).WithLogger(LoggerFunc(func(LogEvent) {}))
+}
+
+// importFakeFixtures imports the fixtures of the directory into the fake database.
+func importFakeFixtures(t *testing.T, db *sql.DB, dir string) {
+	t.Helper()
+	if err := newFakeFixturer(db, dir).ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+}
+
+// insertedRows returns the rows of the insert by the unquoted columns, whatever the order of the columns.
+func insertedRows(query string, args []interface{}) []map[string]interface{} {
+	start, end := strings.Index(query, "("), strings.Index(query, ") VALUES")
+	if start < 0 || end < start {
+		return nil
+	}
+	columns := strings.Split(query[start+1:end], ",")
+	var rows []map[string]interface{}
+	for len(args) >= len(columns) {
+		row := map[string]interface{}{}
+		for i, column := range columns {
+			row[strings.Trim(column, "`\" ")] = args[i]
+		}
+		rows = append(rows, row)
+		args = args[len(columns):]
+	}
+	return rows
+}
+
+// insertedArgs returns the arguments of the inserts into the quoted table, the rows of every insert in brackets.
+func insertedArgs(fake *fakeDB, quotedTable string) string {
+	var inserts []string
+	for _, statement := range fake.executed("INSERT INTO " + quotedTable + " ") {
+		inserts = append(inserts, fmt.Sprint(statement.args))
+	}
+	return strings.Join(inserts, " ")
+}
+
+func TestBinaryValuesReachDriver(t *testing.T) {
+	db, fake := openFakeDB(t, respondTables("files"))
+	dir := writeFixtures(t, map[string]string{
+		"files.yml": "- id: 1\n  data: !base64 /wCA/sOo\n  hash: !hex ff00c328\n",
+	})
+	importFakeFixtures(t, db, dir)
+
+	inserts := fake.executed("INSERT INTO `files`")
+	if len(inserts) != 1 {
+		t.Fatalf("got %d inserts, want 1", len(inserts))
+	}
+	for _, want := range [][]byte{{0xff, 0x00, 0x80, 0xfe, 0xc3, 0xa8}, {0xff, 0x00, 0xc3, 0x28}} {
+		found := false
+		for _, arg := range inserts[0].args {
+			if b, ok := arg.([]byte); ok && bytes.Equal(b, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("insert args %v lack the bytes %x", inserts[0].args, want)
+		}
+	}
+}
+
+func TestQuotedTableNames(t *testing.T) {
+	for _, test := range []struct {
+		table  string
+		quoted string
+	}{
+		{"order", "`order`"},
+		{"order-items", "`order-items`"},
+		{"a`b", "`a``b`"},
+	} {
+		t.Run(test.table, func(t *testing.T) {
+			db, fake := openFakeDB(t, respondTables(test.table))
+			dir := writeFixtures(t, map[string]string{test.table + ".yml": "- id: 1\n"})
+			importFakeFixtures(t, db, dir)
+
+			if truncates := fake.executed("TRUNCATE " + test.quoted); len(truncates) != 1 {
+				t.Errorf("got %d truncates of %s, want 1", len(truncates), test.quoted)
+			}
+			if inserts := fake.executed("INSERT INTO " + test.quoted + " (`id`) VALUES (?)"); len(inserts) != 1 {
+				t.Errorf("got %d inserts into %s, want 1", len(inserts), test.quoted)
+			}
+		})
+	}
+}
+
+func TestSchemaQualifiedTable(t *testing.T) {
+	tables := respondTables()
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if query == (MySQLDialect{}).TableExistsQuery() && len(args) == 2 && args[0] == "analytics" && args[1] == "events" {
+			return []string{"COUNT(*)"}, [][]driver.Value{{int64(1)}}
+		}
+		return tables(query, args)
+	})
+	dir := writeFixtures(t, map[string]string{"analytics.events.yml": "- id: 1\n"})
+	importFakeFixtures(t, db, dir)
+
+	if truncates := fake.executed("TRUNCATE `analytics`.`events`"); len(truncates) != 1 {
+		t.Errorf("got %d truncates of analytics.events, want 1", len(truncates))
+	}
+	if inserts := fake.executed("INSERT INTO `analytics`.`events` (`id`) VALUES (?)"); len(inserts) != 1 {
+		t.Errorf("got %d inserts into analytics.events, want 1", len(inserts))
+	}
+}
+
+func TestGeneratedColumnsStripped(t *testing.T) {
+	tables := respondTables("users")
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if query == (MySQLDialect{}).GeneratedColumnsQuery() {
+			// full_name VARCHAR(255) AS (CONCAT(first_name, ' ', last_name)) STORED
+			return []string{"TABLE_NAME", "COLUMN_NAME"}, [][]driver.Value{{"users", "full_name"}}
+		}
+		return tables(query, args)
+	})
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "- id: 1\n  first_name: Ann\n  last_name: Lee\n" +
+			"- id: 2\n  first_name: Bob\n  last_name: Ray\n  full_name: Bob Ray\n",
+	})
+	importFakeFixtures(t, db, dir)
+
+	inserts := fake.executed("INSERT INTO `users`")
+	if len(inserts) != 1 {
+		t.Fatalf("got %d inserts, want 1", len(inserts))
+	}
+	if strings.Contains(inserts[0].query, "full_name") {
+		t.Errorf("insert %q includes the generated column", inserts[0].query)
+	}
+	for _, arg := range inserts[0].args {
+		if arg == "Bob Ray" {
+			t.Errorf("insert args %v include the generated column value", inserts[0].args)
+		}
+	}
+	if len(inserts[0].args) != 6 {
+		t.Errorf("got %d insert args, want 6", len(inserts[0].args))
+	}
+}
+
+func TestOptionsDropParsedFixtures(t *testing.T) {
+	db, fake := openFakeDB(t, respondTables("users"))
+	dir := writeFixtures(t, map[string]string{"users.yml": "- id: 1\n  name: Ann\n"})
+	f := NewFixturerWithDB(db, "", dir).WithLogger(LoggerFunc(func(LogEvent) {}))
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	f.SetIgnoredColumns("users", []string{"name"}).
+		WithIdentifierQuote(func(name string) string { return `"` + name + `"` })
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if inserts := fake.executed(`INSERT INTO "users" ("id") VALUES (?)`); len(inserts) != 1 {
+		t.Errorf("got %d inserts built with the new options, want 1", len(inserts))
+	}
+}
+
+func TestRepeatWithTemplates(t *testing.T) {
+	db, fake := openFakeDB(t, respondTables("users"))
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "- _repeat: 2\n  id: \"{{ .Number }}\"\n  email: \"user{{.Index}}@{{ \"example.com\" }}\"\n  name: '{{ \"{{ printf \\\"n%d\\\" .Number }}\" }}'\n",
+	})
+	f := NewFixturerWithDB(db, "", dir).WithLogger(LoggerFunc(func(LogEvent) {})).WithTemplates(true)
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	inserts := fake.executed("INSERT INTO `users`")
+	if len(inserts) != 1 {
+		t.Fatalf("got %d inserts, want 1", len(inserts))
+	}
+	args := fmt.Sprint(inserts[0].args)
+	for _, want := range []string{"user0@example.com", "user1@example.com", "n1", "n2"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("insert args %s lack %s", args, want)
+		}
+	}
+}
+
+func TestSqlFixturesVerbatim(t *testing.T) {
+	script := "INSERT INTO notes (body) VALUES ('a; b'); -- c; d\nUPDATE users SET name = 'x;y';\n"
+	db, fake := openFakeDB(t, respondTables("users"))
+	dir := writeFixtures(t, map[string]string{"users.yml": "- id: 1\n", "z.sql": script})
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "a.sql"), []byte("SELECT 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFixturerWithDB(db, "", dir).WithLogger(LoggerFunc(func(LogEvent) {})).WithRecursiveFixtures(true)
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if err := f.Reset(); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	if statements := fake.executed(script); len(statements) != 2 {
+		t.Errorf("got %d executions of the whole script, want 2", len(statements))
+	}
+	if statements := fake.executed("SELECT 1;"); len(statements) != 2 {
+		t.Errorf("got %d executions of the nested script, want 2", len(statements))
+	}
+}
+
+func TestDatabaseRecreated(t *testing.T) {
+	name, fake := newFakeDB(t, respondTables("users"))
+	dir := writeFixtures(t, map[string]string{"users.yml": "- id: 1\n"})
+	schema := filepath.Join(writeFixtures(t, map[string]string{"schema.sql": "CREATE TABLE users (id INT);"}), "schema.sql")
+	f := NewFixturer("", schema, dir, "test_users", "").
+		WithDriver(fakeDriverName).
+		WithDSNBuilder(func(string) string { return name }).
+		WithLogger(LoggerFunc(func(LogEvent) {}))
+
+	for _, test := range []struct {
+		recreate bool
+		env      string
+		want     bool
+	}{
+		{recreate: true, want: true},
+		{recreate: false, want: false},
+		{recreate: true, env: "false", want: false},
+	} {
+		t.Setenv(RecreateEnv, test.env)
+		f.SetRecreateDatabase(test.recreate)
+		if err := f.RecreateDatabaseWithSchemaAndImportFixtures(); err != nil {
+			t.Fatalf("recreate: %v", err)
+		}
+		if got := f.DatabaseRecreated(); got != test.want {
+			t.Errorf("recreate %v with %s=%q: got recreated %v, want %v", test.recreate, RecreateEnv, test.env, got, test.want)
+		}
+	}
+	if creates := fake.executed("CREATE DATABASE"); len(creates) != 1 {
+		t.Errorf("got %d databases created, want 1", len(creates))
+	}
+
+	db, _ := openFakeDB(t, respondTables("users"))
+	external := NewFixturerWithDB(db, "", dir).WithLogger(LoggerFunc(func(LogEvent) {}))
+	if err := external.RecreateDatabaseWithSchemaAndImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if external.DatabaseRecreated() {
+		t.Error("got the database of the caller recreated")
+	}
+}
+
+func TestInsertIgnoreUnsupported(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{"users.yml": "- id: 1\n"})
+	f := NewFixturer("", "", dir, "test_users", "").WithDialect(SQLServerDialect{}).WithInsertIgnore(true)
+	if _, _, err := f.BuildSQL("users"); err == nil || !strings.Contains(err.Error(), "does not support insert ignore") {
+		t.Errorf("got error %v, want the unsupported insert ignore", err)
+	}
+
+	f.WithDialect(MySQLDialect{})
+	query, _, err := f.BuildSQL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(query, "INSERT IGNORE INTO") {
+		t.Errorf("got %q, want INSERT IGNORE", query)
+	}
+}
+
+func TestDatabaseNameQuoted(t *testing.T) {
+	for _, test := range []struct {
+		dialect Dialect
+		drop    string
+		create  string
+	}{
+		{PostgresDialect{}, `DROP DATABASE IF EXISTS "Test-DB"""`, `CREATE DATABASE "Test-DB"""`},
+		{MySQLDialect{}, "DROP DATABASE IF EXISTS `Test-DB\"`", "CREATE DATABASE `Test-DB\"`"},
+	} {
+		db, fake := openFakeDB(t, nil)
+		if err := test.dialect.DropDatabase(context.Background(), db, "", `Test-DB"`); err != nil {
+			t.Fatal(err)
+		}
+		if err := test.dialect.CreateDatabase(context.Background(), db, "", `Test-DB"`); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{test.drop, test.create} {
+			if statements := fake.executed(want); len(statements) != 1 || statements[0].query != want {
+				t.Errorf("%T: got %v, want %s", test.dialect, statements, want)
+			}
+		}
+	}
+}
+
+func TestStreamedFixtureOptions(t *testing.T) {
+	padding := "# " + strings.Repeat("x", StreamingFileSizeThreshold) + "\n"
+	db, fake := openFakeDB(t, respondTables("users"))
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "- _repeat: 2\n  id: \"{{ .Number }}\"\n- id: \"2\"\n- id: \"3\"\n" + padding,
+	})
+	f := NewFixturerWithDB(db, "", dir).WithLogger(LoggerFunc(func(LogEvent) {})).
+		WithStreaming(true).SetKeyColumns("users", "id").SetDedupeByPrimaryKey(true)
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	inserts := fake.executed("INSERT INTO `users`")
+	if len(inserts) != 1 {
+		t.Fatalf("got %d inserts, want 1", len(inserts))
+	}
+	if got := fmt.Sprint(inserts[0].args); got != "[1 2 3]" {
+		t.Errorf("got insert args %s, want the repeated rows without the duplicate", got)
+	}
+
+	f.WithTemplates(true)
+	if err := f.ImportFixtures(); err == nil || !strings.Contains(err.Error(), "can't be templates") {
+		t.Errorf("got error %v, want the streamed template rejected", err)
+	}
+
+	dir = writeFixtures(t, map[string]string{"users.yml": "users:\n  - id: 1\n" + padding})
+	if err := f.WithTemplates(false).ImportFixturesFrom(dir); err == nil || !strings.Contains(err.Error(), "multi-table") {
+		t.Errorf("got error %v, want the streamed multi-table fixture rejected", err)
+	}
+}
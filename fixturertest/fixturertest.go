@@ -0,0 +1,41 @@
+// Package fixturertest loads the fixtures in the tests.
+package fixturertest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/44hapa/fixturer"
+)
+
+// Setup recreates the database with the schema and imports the fixtures, failing the test on an error.
+// The returned cleanup drops the database if Setup recreated it, so the databases kept on purpose
+// (see fixturer.IFixturer.DatabaseRecreated) and the database of the caller are left intact.
+// It is registered with t.Cleanup as well, so calling it is optional:
+//
+//	func TestUsers(t *testing.T) {
+//		fixturertest.Setup(t, fixturer.NewFixturer(dbConf, schema, fixturesPath, "test_users", ""))
+//		...
+//	}
+func Setup(tb testing.TB, f fixturer.IFixturer) (cleanup func()) {
+	tb.Helper()
+
+	// The database is not dropped after a failure, so it may be inspected (see WithKeepDatabaseOnFailure).
+	if err := f.RecreateDatabaseWithSchemaAndImportFixtures(); err != nil {
+		tb.Fatalf("fixturertest: load fixtures: %v", err)
+	}
+	if !f.DatabaseRecreated() {
+		return func() {}
+	}
+
+	var once sync.Once
+	cleanup = func() {
+		once.Do(func() {
+			if err := f.DropDatabase(); err != nil {
+				tb.Errorf("fixturertest: drop database: %v", err)
+			}
+		})
+	}
+	tb.Cleanup(cleanup)
+	return cleanup
+}
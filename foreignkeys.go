@@ -0,0 +1,131 @@
+package fixturer
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// querier runs the introspection queries with either the connection or a transaction.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// introspector returns the querier of the introspection queries run while parsing, the transaction
+// of ImportFixturesTx or the connection, or nil when not connected.
+func (this *Fixturer) introspector() querier {
+	if this.introspectionTx != nil {
+		return this.introspectionTx
+	}
+	if this.db != nil {
+		return this.db
+	}
+	return nil
+}
+
+// tablesForeignKeys returns the tables of the current database referenced by every table.
+func (this *Fixturer) tablesForeignKeys(q querier) (map[string][]string, error) {
+	rows, err := q.Query(this.dialect.ForeignKeysQuery())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	references := map[string][]string{}
+	for rows.Next() {
+		var table, referenced string
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, err
+		}
+		references[table] = append(references[table], referenced)
+	}
+	return references, rows.Err()
+}
+
+// levelTablesByForeignKeys groups the tables into levels, so every table goes after the levels
+// of the tables it references.
+func (this *Fixturer) levelTablesByForeignKeys(q querier, tables []string) ([][]string, error) {
+	references, err := this.tablesForeignKeys(q)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := levelTablesByReferences(tables, references, this.deferredTables())
+	if err != nil {
+		return nil, fmt.Errorf("foreign keys: %w", err)
+	}
+	return levels, nil
+}
+
+// sortTablesByReferences topologically sorts the tables. References to tables out of the list
+// and self references are ignored. Tables of the same level keep their order.
+func sortTablesByReferences(tables []string, references map[string][]string) ([]string, error) {
+	levels, err := levelTablesByReferences(tables, references, nil)
+	if err != nil {
+		return nil, err
+	}
+	return flattenLevels(levels), nil
+}
+
+// flattenLevels returns the tables of the levels in order.
+func flattenLevels(levels [][]string) []string {
+	var tables []string
+	for _, level := range levels {
+		tables = append(tables, level...)
+	}
+	return tables
+}
+
+// levelTablesByReferences groups the tables into levels referencing only the tables of the previous levels,
+// so the tables of a level do not depend on each other. A cycle is broken at the first of its deferred tables,
+// whose pending references are dropped.
+func levelTablesByReferences(tables []string, references map[string][]string, deferred map[string]struct{}) ([][]string, error) {
+	pending := make(map[string]map[string]struct{}, len(tables))
+	for _, table := range tables {
+		pending[table] = map[string]struct{}{}
+	}
+	for _, table := range tables {
+		for _, referenced := range references[table] {
+			if _, find := pending[referenced]; find && referenced != table {
+				pending[table][referenced] = struct{}{}
+			}
+		}
+	}
+
+	var levels [][]string
+	for len(pending) > 0 {
+		var ready []string
+		for _, table := range tables {
+			if parents, find := pending[table]; find && len(parents) == 0 {
+				ready = append(ready, table)
+			}
+		}
+		if len(ready) == 0 {
+			for _, table := range tables {
+				if _, find := deferred[table]; find && pending[table] != nil {
+					pending[table] = map[string]struct{}{}
+					ready = append(ready, table)
+					break
+				}
+			}
+		}
+		if len(ready) == 0 {
+			cycle := make([]string, 0, len(pending))
+			for table := range pending {
+				cycle = append(cycle, table)
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("references of %s form a cycle", strings.Join(cycle, ", "))
+		}
+
+		for _, table := range ready {
+			delete(pending, table)
+			for _, parents := range pending {
+				delete(parents, table)
+			}
+		}
+		levels = append(levels, ready)
+	}
+
+	return levels, nil
+}
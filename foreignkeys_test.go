@@ -0,0 +1,59 @@
+package fixturer
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+// respondForeignKeys makes the fake MySQL database have the tables and the foreign keys of the child
+// tables to their parents.
+func respondForeignKeys(references map[string]string, tables ...string) func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	respond := respondTables(tables...)
+	return func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if query != (MySQLDialect{}).ForeignKeysQuery() {
+			return respond(query, args)
+		}
+		var rows [][]driver.Value
+		for table, referenced := range references {
+			rows = append(rows, []driver.Value{table, referenced})
+		}
+		return []string{"TABLE_NAME", "REFERENCED_TABLE_NAME"}, rows
+	}
+}
+
+func TestForeignKeysOrder(t *testing.T) {
+	references := map[string]string{"comments": "posts", "posts": "users"}
+	db, fake := openFakeDB(t, respondForeignKeys(references, "comments", "posts", "users"))
+	dir := writeFixtures(t, map[string]string{
+		"comments.yml": "- id: 1\n  post_id: 1\n",
+		"posts.yml":    "- id: 1\n  user_id: 1\n",
+		"users.yml":    "- id: 1\n",
+	})
+	if err := newFakeFixturer(db, dir).SetDisableForeignKeyChecks(false).ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	var inserted, deleted []string
+	for _, statement := range fake.executed("") {
+		if strings.HasPrefix(statement.query, "INSERT INTO `") {
+			inserted = append(inserted, strings.SplitN(statement.query, "`", 3)[1])
+		}
+		if strings.HasPrefix(statement.query, "DELETE FROM `") {
+			deleted = append(deleted, strings.SplitN(statement.query, "`", 3)[1])
+		}
+	}
+	if got, want := strings.Join(inserted, ","), "users,posts,comments"; got != want {
+		t.Errorf("got inserts of %s, want %s", got, want)
+	}
+	if got, want := strings.Join(deleted, ","), "comments,posts,users"; got != want {
+		t.Errorf("got deletes of %s, want %s", got, want)
+	}
+
+	references["users"] = "comments"
+	db, _ = openFakeDB(t, respondForeignKeys(references, "comments", "posts", "users"))
+	err := newFakeFixturer(db, dir).SetDisableForeignKeyChecks(false).ImportFixtures()
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("got error %v, want the cycle of the references", err)
+	}
+}
@@ -0,0 +1,79 @@
+package fixturer
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// WithFixturesFS loads the fixtures of the directory of the file system instead of the fixtures directory,
+// e.g. the fixtures embedded into the test binary:
+//
+//	//go:embed testdata/fixtures
+//	var fixtures embed.FS
+//
+//	f.WithFixturesFS(fixtures, "testdata/fixtures")
+//
+// The files are read into memory like the archive entries, so they are never streamed, and neither
+// the manifest nor the .sql fixtures are applied. !include is not supported. Nil fsys restores the fixtures directory.
+func (this *Fixturer) WithFixturesFS(fsys fs.FS, dir string) IFixturer {
+	this.fixturesFS, this.fixturesFSDir = fsys, dir
+	// Another file system may have the same source name.
+	this.dropCache()
+	return this
+}
+
+// fsFiles returns the fixture files of the directory of the file system sorted by path,
+// including the ones of the subdirectories with WithRecursiveFixtures.
+func (this *Fixturer) fsFiles(fsys fs.FS, dir string) ([]fixtureFile, error) {
+	if dir == "" {
+		dir = "."
+	}
+	var files []fixtureFile
+	err := fs.WalkDir(fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name != dir && !this.recursiveFixtures {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if fixtureExt(entry.Name()) == "" {
+			return nil
+		}
+
+		sub := strings.TrimPrefix(path.Dir(name), dir)
+		sub = strings.TrimPrefix(sub, "/")
+		if sub == "" && entry.Name() == ManifestFileName {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		file := fixtureFile{FileInfo: info, path: name, data: data}
+		if this.subdirectorySchemas && sub != "" {
+			if strings.Contains(sub, "/") {
+				return fmt.Errorf("fixture %s is nested too deep for the subdirectory to be its schema", name)
+			}
+			file.schema = sub
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
@@ -0,0 +1,93 @@
+package fixturer
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// globFiles returns the regular files matching the pattern.
+// A "**" element of the pattern matches zero or more directories.
+func globFiles(pattern string) ([]fixtureFile, error) {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	if !strings.Contains(pattern, "**") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		var resultSlice []fixtureFile
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			resultSlice = append(resultSlice, fixtureFile{FileInfo: info, path: match})
+		}
+		return resultSlice, nil
+	}
+
+	patternParts := strings.Split(pattern, "/")
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var resultSlice []fixtureFile
+	err := filepath.Walk(globRoot(patternParts), func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if matchGlobParts(patternParts, strings.Split(filepath.ToSlash(filePath), "/")) {
+			resultSlice = append(resultSlice, fixtureFile{FileInfo: info, path: filePath})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resultSlice, nil
+}
+
+// globRoot returns the leading directory of the pattern that contains no meta characters.
+func globRoot(patternParts []string) string {
+	i := 0
+	for i < len(patternParts)-1 && !strings.ContainsAny(patternParts[i], `*?[\`) {
+		i++
+	}
+	root := strings.Join(patternParts[:i], "/")
+	if root == "" {
+		if len(patternParts) > 1 && patternParts[0] == "" {
+			return "/"
+		}
+		return "."
+	}
+	return filepath.FromSlash(root)
+}
+
+func matchGlobParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,27 @@
+package fixturer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFixturesGlobDoubleStar(t *testing.T) {
+	db, fake := openFakeDB(t, respondTables("users", "orders", "items", "notes"))
+	dir := writeFixtures(t, map[string]string{
+		"users.yml":          "- id: 1\n",
+		"shop/orders.yml":    "- id: 2\n",
+		"shop/eu/items.yml":  "- id: 3\n",
+		"shop/eu/notes.json": `[{"id": 4}]`,
+		"shop/eu/readme.txt": "not a fixture",
+	})
+	f := newFakeFixturer(db, dir).WithFixturesGlob(filepath.Join(dir, "**", "*.yml"))
+	if err := f.ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	for table, want := range map[string]string{"`users`": "[1]", "`orders`": "[2]", "`items`": "[3]", "`notes`": ""} {
+		if got := insertedArgs(fake, table); got != want {
+			t.Errorf("got %s inserted into %s, want %q", got, table, want)
+		}
+	}
+}
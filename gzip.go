@@ -0,0 +1,74 @@
+package fixturer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// gzipExt is the extension of the gzip-compressed fixture and schema files, e.g. users.yml.gz or schema.sql.gz.
+// They are decompressed on the fly.
+const gzipExt = ".gz"
+
+// readFile reads the file, decompressing a .gz one.
+func readFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return gunzip(path, data)
+}
+
+// fixtureData returns the content of the fixture file, decompressed if needed.
+func fixtureData(f fixtureFile) ([]byte, error) {
+	if f.data == nil {
+		return readFile(f.path)
+	}
+	return gunzip(f.path, f.data)
+}
+
+// gunzip decompresses the content of the .gz file, the content of the other files is returned as is.
+func gunzip(path string, data []byte) ([]byte, error) {
+	if !strings.HasSuffix(path, gzipExt) {
+		return data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer reader.Close()
+
+	if data, err = ioutil.ReadAll(reader); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// openFile opens the file for reading, decompressing a .gz one while it is read.
+func openFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil || !strings.HasSuffix(path, gzipExt) {
+		return file, err
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return gzipFile{Reader: reader, file: file}, nil
+}
+
+// gzipFile closes both the decompressing reader and the file.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (this gzipFile) Close() error {
+	this.Reader.Close()
+	return this.file.Close()
+}
@@ -0,0 +1,410 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TablesImportError is returned by the import with per-table transactions when some of the tables failed.
+// The succeeded tables stay committed.
+type TablesImportError struct {
+	Succeeded []string
+	Failed    map[string]error
+}
+
+func (this *TablesImportError) Error() string {
+	failed := make([]string, 0, len(this.Failed))
+	for table, err := range this.Failed {
+		failed = append(failed, fmt.Sprintf("%s: %v", table, err))
+	}
+	sort.Strings(failed)
+	return fmt.Sprintf("%d of %d tables failed to load: %s",
+		len(this.Failed), len(this.Failed)+len(this.Succeeded), strings.Join(failed, "; "))
+}
+
+// insertWorker executes the table inserts it receives inside its own transaction.
+// The transaction is left open so the coordinator can commit or roll back all the workers together.
+// With per-table transactions every insert is committed by the worker itself and its result is collected.
+type insertWorker struct {
+	tx      workerTx
+	err     error
+	results map[string]error
+	// pending is the number of the dispatched queries not processed yet by the workers.
+	pending *sync.WaitGroup
+}
+
+// workerTx is the transaction of a worker, or its connection in the autocommit mode (see WithoutTransaction).
+type workerTx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// autocommitConn runs the statements of a worker on a dedicated connection, so the session variables still apply.
+// Every statement is committed on its own, Commit and Rollback just release the connection.
+type autocommitConn struct {
+	conn *sql.Conn
+	ctx  context.Context
+}
+
+func (this *autocommitConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return this.conn.ExecContext(this.ctx, query, args...)
+}
+
+func (this *autocommitConn) Commit() error {
+	return this.conn.Close()
+}
+
+func (this *autocommitConn) Rollback() error {
+	return this.conn.Close()
+}
+
+func (this *insertWorker) run(f *Fixturer, queries <-chan *insertQuery, failed *int32) {
+	for query := range queries {
+		this.process(f, query, failed)
+		this.pending.Done()
+	}
+}
+
+// process executes a single query received by the worker.
+func (this *insertWorker) process(f *Fixturer, query *insertQuery, failed *int32) {
+	start := time.Now()
+	if f.txPerTable {
+		err := this.execInOwnTx(f, query)
+		if err != nil {
+			err = queryError(PhaseInserting, query, err)
+		}
+		this.results[query.table] = err
+		if err == nil {
+			f.tableInserted(query, time.Since(start))
+		}
+		return
+	}
+	// Keep draining the channel after a failure so the coordinator is never blocked.
+	if this.err != nil || atomic.LoadInt32(failed) != 0 {
+		return
+	}
+	if err := this.exec(f, query); err != nil {
+		this.err = queryError(PhaseInserting, query, err)
+		atomic.StoreInt32(failed, 1)
+		return
+	}
+	f.tableInserted(query, time.Since(start))
+}
+
+// tableInserted reports the table inserted by a worker.
+func (this *Fixturer) tableInserted(query *insertQuery, duration time.Duration) {
+	this.progress.step(query.table, query.rowsCount())
+	this.logger.Log(LogEvent{
+		Level:    LevelDebug,
+		Message:  "Table inserted",
+		Phase:    PhaseInserting,
+		Table:    query.table,
+		File:     query.file,
+		Duration: duration,
+		Rows:     query.rowsCount(),
+	})
+}
+
+func (this *insertWorker) begin(f *Fixturer) error {
+	var tx workerTx
+	if f.withoutTransaction {
+		conn, err := f.db.Conn(f.context())
+		if err != nil {
+			return err
+		}
+		tx = &autocommitConn{conn: conn, ctx: f.context()}
+	} else {
+		sqlTx, err := f.db.BeginTx(f.context(), nil)
+		if err != nil {
+			return err
+		}
+		tx = ctxTx{Tx: sqlTx, ctx: f.context()}
+	}
+	this.tx = tx
+	for _, query := range f.statementTimeoutQueries() {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	if !f.disableForeignKeys {
+		return nil
+	}
+	// FOREIGN_KEY_CHECKS is a session variable, so every worker connection needs its own.
+	_, err := tx.Exec(f.dialect.ForeignKeyChecks(false))
+	return err
+}
+
+// clear deletes the rows of the tables, child tables first, in the worker transaction.
+func (this *insertWorker) clear(f *Fixturer, tables []string) error {
+	if err := this.begin(f); err != nil {
+		return err
+	}
+
+	f.progress.start(PhaseTruncating, len(tables))
+	for i := len(tables) - 1; i >= 0; i-- {
+		f.progress.step(tables[i], 0)
+		if f.skipsClear(tables[i]) {
+			continue
+		}
+		start := time.Now()
+		if _, err := this.tx.Exec("DELETE FROM " + f.quoteTable(tables[i])); err != nil {
+			return &FixtureError{Phase: PhaseTruncating, Table: tables[i], Err: err}
+		}
+		f.logger.Log(LogEvent{
+			Level: LevelDebug, Message: "Table cleared", Phase: PhaseTruncating, Table: tables[i], Duration: time.Since(start),
+		})
+	}
+	return nil
+}
+
+func (this *insertWorker) commit(f *Fixturer) error {
+	if f.disableForeignKeys {
+		if _, err := this.tx.Exec(f.dialect.ForeignKeyChecks(true)); err != nil {
+			this.tx.Rollback()
+			return err
+		}
+	}
+	return this.tx.Commit()
+}
+
+func (this *insertWorker) exec(f *Fixturer, query *insertQuery) error {
+	if this.tx == nil {
+		if err := this.begin(f); err != nil {
+			return err
+		}
+	}
+	return this.insert(f, query)
+}
+
+func (this *insertWorker) execInOwnTx(f *Fixturer, query *insertQuery) error {
+	defer func() { this.tx = nil }()
+
+	if err := this.begin(f); err != nil {
+		if this.tx != nil {
+			this.tx.Rollback()
+		}
+		return err
+	}
+	if f.clearsInTableTx() && !f.skipsClear(query.table) && f.tableClearStrategy(query.table) != ClearRecreate {
+		// TRUNCATE would commit the transaction implicitly.
+		if _, err := this.tx.Exec("DELETE FROM " + f.quoteTable(query.table)); err != nil {
+			this.tx.Rollback()
+			return err
+		}
+	}
+	if err := this.insert(f, query); err != nil {
+		this.tx.Rollback()
+		return err
+	}
+	return this.commit(f)
+}
+
+func (this *insertWorker) insert(f *Fixturer, query *insertQuery) error {
+	if query.streamed {
+		return this.streamInsert(f, query)
+	}
+	if len(query.rows) >= BulkLoadRowsThreshold && len(query.updates) == 0 && f.bulkLoads(query.table, query.columns, query.rows) {
+		err := this.bulkLoad(query.table, query.columns, query.rows)
+		if !isLocalInfileDisabled(err) {
+			return err
+		}
+		f.logger.Log(LogEvent{
+			Level:   LevelWarn,
+			Message: fmt.Sprintf("LOAD DATA LOCAL INFILE is not permitted, fall back to INSERT for %s. Origin error: %v", query.table, err),
+			Phase:   PhaseInserting,
+			Table:   query.table,
+			File:    query.file,
+			Err:     err,
+		})
+	}
+
+	return this.withIdentityInsert(f, query.table, query.columns, func() error {
+		for _, qb := range query.qbs {
+			queryString, queryValues, err := qb.ToSql()
+			if err != nil {
+				return err
+			}
+			if _, err := this.tx.Exec(queryString, queryValues...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withIdentityInsert runs the inserts of the table allowing the explicit values of its identity column,
+// if the columns include it and the dialect needs it allowed.
+func (this *insertWorker) withIdentityInsert(f *Fixturer, table string, columns []string, insert func() error) error {
+	d, ok := f.dialect.(IdentityInsertDialect)
+	if !ok || !f.hasIdentityColumn(table, columns) {
+		return insert()
+	}
+	if _, err := this.tx.Exec(d.IdentityInsert(f.quoteTable(table), true)); err != nil {
+		return err
+	}
+	err := insert()
+	// A session allows the identity insert for a single table at a time.
+	if _, offErr := this.tx.Exec(d.IdentityInsert(f.quoteTable(table), false)); err == nil {
+		err = offErr
+	}
+	return err
+}
+
+// insertParsedData dispatches every table insert to a pool of insertGoroutinesCnt workers.
+// Each worker runs its own transaction; they are all committed only if every insert succeeded,
+// otherwise all of them are rolled back.
+// The levels are inserted one after another. Since a worker transaction does not see the rows of the others,
+// the tables of the next level wait for the previous ones only if every table is committed on its own
+// (see WithTxPerTable and WithoutTransaction), otherwise a single worker inserts the queries one by one.
+// The tables of clearInTx are cleared by a single worker in its transaction before the inserts.
+// The deferred columns (see WithDeferredColumns) are updated after the inserts by the first worker.
+func (this *Fixturer) insertParsedData(levels [][]*insertQuery, clearInTx []string) error {
+	var queries []*insertQuery
+	levelSize := 0
+	for _, level := range levels {
+		queries = append(queries, level...)
+		if len(level) > levelSize {
+			levelSize = len(level)
+		}
+	}
+
+	// The deferred updates run in a single transaction, which must see the rows of every table.
+	deferred := hasDeferredUpdates(queries)
+	workersCnt := this.insertGoroutinesCnt
+	if (len(levels) > 1 || deferred) && !this.txPerTable && !this.withoutTransaction {
+		workersCnt = 1
+	}
+	if this.dialect.SingleWriter() {
+		workersCnt = 1
+	}
+	if workersCnt > levelSize {
+		workersCnt = levelSize
+	}
+	// The clearing worker is needed even if there is nothing to insert.
+	if len(clearInTx) > 0 {
+		workersCnt = 1
+	}
+
+	queriesCh := make(chan *insertQuery, InsertChannelCapacity)
+	workers := make([]*insertWorker, workersCnt)
+	var pending sync.WaitGroup
+	for i := range workers {
+		workers[i] = &insertWorker{results: map[string]error{}, pending: &pending}
+	}
+	if len(clearInTx) > 0 {
+		if err := workers[0].clear(this, clearInTx); err != nil {
+			if workers[0].tx != nil {
+				workers[0].tx.Rollback()
+			}
+			return err
+		}
+	}
+
+	var failed int32
+	var wg sync.WaitGroup
+	wg.Add(workersCnt)
+	for i := range workers {
+		go func(w *insertWorker) {
+			defer wg.Done()
+			w.run(this, queriesCh, &failed)
+		}(workers[i])
+	}
+
+	this.progress.start(PhaseInserting, len(queries))
+	for _, level := range levels {
+		pending.Add(len(level))
+		for _, query := range level {
+			queriesCh <- query
+		}
+		// A single worker keeps the order by itself.
+		if workersCnt > 1 {
+			pending.Wait()
+		}
+	}
+	close(queriesCh)
+	wg.Wait()
+
+	if deferred && this.txPerTable {
+		workers[0].updateDeferredInOwnTx(this, queries, failedTables(workers))
+	} else if deferred && atomic.LoadInt32(&failed) == 0 {
+		if err := workers[0].updateDeferred(this, queries); err != nil {
+			workers[0].err = err
+		}
+	}
+
+	rowsCnt := 0
+	for _, query := range queries {
+		rowsCnt += query.rowsCount()
+	}
+
+	if this.txPerTable {
+		return this.collectTablesResults(workers, rowsCnt)
+	}
+
+	var firstErr error
+	for _, w := range workers {
+		if w.err != nil && firstErr == nil {
+			firstErr = w.err
+		}
+	}
+	if firstErr != nil {
+		for _, w := range workers {
+			if w.tx != nil {
+				w.tx.Rollback()
+			}
+		}
+		return firstErr
+	}
+
+	for _, w := range workers {
+		if w.tx == nil {
+			continue
+		}
+		if err := w.commit(this); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		this.setLastImport(this.cache().tables, rowsCnt)
+	}
+
+	return firstErr
+}
+
+// failedTables returns the errors of the tables failed by any of the workers.
+func failedTables(workers []*insertWorker) map[string]error {
+	failed := map[string]error{}
+	for _, w := range workers {
+		for table, err := range w.results {
+			if err != nil {
+				failed[table] = err
+			}
+		}
+	}
+	return failed
+}
+
+func (this *Fixturer) collectTablesResults(workers []*insertWorker, rowsCnt int) error {
+	tablesErr := &TablesImportError{Failed: failedTables(workers)}
+	for _, w := range workers {
+		for table := range w.results {
+			if _, find := tablesErr.Failed[table]; !find {
+				tablesErr.Succeeded = append(tablesErr.Succeeded, table)
+			}
+		}
+	}
+	sort.Strings(tablesErr.Succeeded)
+
+	if len(tablesErr.Failed) > 0 {
+		return tablesErr
+	}
+	this.setLastImport(this.cache().tables, rowsCnt)
+	return nil
+}
@@ -0,0 +1,231 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	mysqlErrDbAccessDenied = 1044
+	mysqlErrAccessDenied   = 1045
+	mysqlErrBadDb          = 1049
+	mysqlErrNoSuchTable    = 1146
+)
+
+// The errors of Ping, the returned errors wrap them along with the driver error.
+var (
+	ErrDatabaseNotExist  = errors.New("database does not exist")
+	ErrAuthFailed        = errors.New("authentication failed")
+	ErrServerUnreachable = errors.New("server is unreachable")
+)
+
+// Ping checks the database is reachable, e.g. before a long import. The error wraps ErrDatabaseNotExist,
+// ErrAuthFailed or ErrServerUnreachable when the cause is known. It uses the open connection or connects for the call.
+func (this *Fixturer) Ping() error {
+	return this.PingContext(context.Background())
+}
+
+// PingContext is Ping with the context.
+func (this *Fixturer) PingContext(ctx context.Context) error {
+	return this.withContext(ctx, "ping", this.ping)
+}
+
+func (this *Fixturer) ping() error {
+	if this.db == nil {
+		if err := this.ensureDbConnected(); err != nil {
+			return this.pingError(err)
+		}
+		defer this.ensureDbDisconnected()
+	}
+	return this.pingError(this.db.PingContext(this.context()))
+}
+
+func (this *Fixturer) pingError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var mysqlErr *mysql.MySQLError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrBadDb:
+		return fmt.Errorf("%w: %s: %v", ErrDatabaseNotExist, this.dbName, err)
+	case errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlErrAccessDenied || mysqlErr.Number == mysqlErrDbAccessDenied):
+		return fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	case errors.As(err, &netErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn):
+		return fmt.Errorf("%w: %v", ErrServerUnreachable, err)
+	}
+	return err
+}
+
+// existingTables returns the tables of the current database.
+func (this *Fixturer) existingTables() (map[string]struct{}, error) {
+	rows, err := this.db.Query(this.dialect.TablesQuery())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := map[string]struct{}{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables[table] = struct{}{}
+	}
+	return tables, rows.Err()
+}
+
+// schemaTableExists checks whether the table of another schema exists.
+func (this *Fixturer) schemaTableExists(schema, table string) (bool, error) {
+	var cnt int
+	err := this.db.QueryRow(this.rebind(this.dialect.TableExistsQuery()), schema, table).Scan(&cnt)
+	return cnt > 0, err
+}
+
+// checkTablesExist makes sure every parsed fixture has its table in the database,
+// so a typo in a fixture name fails before anything is truncated.
+func (this *Fixturer) checkTablesExist() error {
+	tables, err := this.existingTables()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, query := range this.cache().queries {
+		_, find := tables[query.table]
+		if i := strings.Index(query.table, "."); i > 0 {
+			if find, err = this.schemaTableExists(query.table[:i], query.table[i+1:]); err != nil {
+				return err
+			}
+		}
+		if !find {
+			missing = append(missing, fmt.Sprintf("%s (fixture %s)", query.table, query.file))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("tables do not exist in database %s: %s", this.dbName, strings.Join(missing, ", "))
+}
+
+// SchemaMismatchError is returned by VerifySchema listing the fixture tables missing in the database
+// and the fixture columns missing in the tables.
+type SchemaMismatchError struct {
+	MissingTables  []string
+	MissingColumns map[string][]string
+}
+
+func (this *SchemaMismatchError) Error() string {
+	var problems []string
+	if len(this.MissingTables) > 0 {
+		problems = append(problems, "missing tables: "+strings.Join(this.MissingTables, ", "))
+	}
+	tables := make([]string, 0, len(this.MissingColumns))
+	for table := range this.MissingColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		problems = append(problems, fmt.Sprintf("table %s misses columns: %s", table, strings.Join(this.MissingColumns[table], ", ")))
+	}
+	return "schema does not match the fixtures: " + strings.Join(problems, "; ")
+}
+
+// VerifySchema checks every fixture table exists and has the columns used by its fixture, returning
+// *SchemaMismatchError listing all the mismatches. The fixtures are parsed unless they are cached already.
+// It uses the open connection or connects for the call.
+func (this *Fixturer) VerifySchema() error {
+	files, err := this.fixtureFiles()
+	if err != nil {
+		return err
+	}
+	if this.db == nil {
+		if err := this.ensureDbConnected(); err != nil {
+			return err
+		}
+		defer this.ensureDbDisconnected()
+	}
+	if err := this.parseFixtures(files); err != nil {
+		return err
+	}
+
+	mismatch := &SchemaMismatchError{MissingColumns: map[string][]string{}}
+	for _, query := range this.queriesInOrder(this.cache().tables, true) {
+		columns, err := this.tableColumns(query.table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			mismatch.MissingTables = append(mismatch.MissingTables, fmt.Sprintf("%s (fixture %s)", query.table, query.file))
+			continue
+		}
+		// The columns of a streamed fixture are known only at load time.
+		for _, column := range query.columns {
+			if _, find := columns[column]; !find {
+				mismatch.MissingColumns[query.table] = append(mismatch.MissingColumns[query.table], column)
+			}
+		}
+		sort.Strings(mismatch.MissingColumns[query.table])
+	}
+
+	if len(mismatch.MissingTables) == 0 && len(mismatch.MissingColumns) == 0 {
+		return nil
+	}
+	sort.Strings(mismatch.MissingTables)
+	return mismatch
+}
+
+// tableColumns returns the columns of the table, none if the table does not exist.
+// A dotted table name is a table of another schema.
+func (this *Fixturer) tableColumns(table string) (map[string]struct{}, error) {
+	query := this.dialect.ColumnsQuery(false)
+	args := []interface{}{table}
+	if i := strings.Index(table, "."); i > 0 {
+		query = this.dialect.ColumnsQuery(true)
+		args = []interface{}{table[:i], table[i+1:]}
+	}
+
+	rows, err := this.db.Query(this.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]struct{}{}
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns[column] = struct{}{}
+	}
+	return columns, rows.Err()
+}
+
+// CountRows returns the rows count of the table, e.g. to check the import in a test.
+// It uses the open connection or connects for the call.
+func (this *Fixturer) CountRows(table string) (int, error) {
+	if this.db == nil {
+		if err := this.ensureDbConnected(); err != nil {
+			return 0, err
+		}
+		defer this.ensureDbDisconnected()
+	}
+
+	var cnt int
+	err := this.db.QueryRow("SELECT COUNT(*) FROM " + this.quoteTable(table)).Scan(&cnt)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrNoSuchTable {
+		return 0, fmt.Errorf("table %s does not exist in database %s", table, this.dbName)
+	}
+	return cnt, err
+}
@@ -0,0 +1,155 @@
+package fixturer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of the fixture files, the table is named after the file without the extension.
+// A fixture file may be compressed, e.g. users.yml.gz.
+const (
+	ymlFixtureExt  = ".yml"
+	jsonFixtureExt = ".json"
+	csvFixtureExt  = ".csv"
+)
+
+// fixtureExt returns the extension of the fixture file name including .gz, if any, or an empty string for another file.
+func fixtureExt(name string) string {
+	if format := fixtureFormat(name); format != "" {
+		return name[strings.LastIndex(name, format):]
+	}
+	return ""
+}
+
+// fixtureFormat returns the extension of the fixture file name without .gz, or an empty string for another file.
+func fixtureFormat(name string) string {
+	switch ext := filepath.Ext(strings.TrimSuffix(name, gzipExt)); ext {
+	case ymlFixtureExt, jsonFixtureExt, csvFixtureExt:
+		return ext
+	}
+	return ""
+}
+
+// isCSVFixture reports whether the fixture file is decoded as CSV.
+func isCSVFixture(path string) bool {
+	return fixtureFormat(path) == csvFixtureExt
+}
+
+// isJSONFixture reports whether the fixture file is decoded as JSON.
+func isJSONFixture(path string) bool {
+	return fixtureFormat(path) == jsonFixtureExt
+}
+
+// decodeJSONTables decodes the content of a .json fixture, an array of the row objects or an object
+// of the table names to their arrays of rows, e.g. {"users": [{"id": 1}], "user_profiles": [{"user_id": 1}]}.
+// The custom tags of YAML have no JSON counterpart, the nested objects and arrays are inserted as JSON text.
+func (this *fixtureDecoder) decodeJSONTables(data []byte) ([]fixtureTable, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var root json.RawMessage
+	if err := decoder.Decode(&root); err == io.EOF {
+		return []fixtureTable{{}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("offset %d: unexpected data after the fixture", decoder.InputOffset())
+	}
+
+	names, lists, err := jsonMultiTable(root)
+	if err != nil {
+		return nil, err
+	}
+	if names != nil {
+		tables := make([]fixtureTable, 0, len(names))
+		for i, name := range names {
+			decoder := *this
+			decoder.table = name
+			rows, err := decoder.jsonRows(lists[i])
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", name, err)
+			}
+			tables = append(tables, fixtureTable{table: name, rows: rows, multiTable: true})
+		}
+		return tables, nil
+	}
+
+	if this.strict && !bytes.HasPrefix(root, []byte("[")) {
+		return nil, fmt.Errorf("fixture must be an array of rows or an object of tables to their rows")
+	}
+	rows, err := this.jsonRows(root)
+	if err != nil {
+		return nil, err
+	}
+	return []fixtureTable{{rows: rows}}, nil
+}
+
+// jsonMultiTable returns the table names and the arrays of a multi-table fixture in the declaration order,
+// or nothing if the root is not an object of arrays.
+func jsonMultiTable(root json.RawMessage) ([]string, []json.RawMessage, error) {
+	if !bytes.HasPrefix(root, []byte("{")) {
+		return nil, nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(root))
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	var lists []json.RawMessage
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var list json.RawMessage
+		if err := decoder.Decode(&list); err != nil {
+			return nil, nil, err
+		}
+		if !bytes.HasPrefix(list, []byte("[")) {
+			return nil, nil, nil
+		}
+		names, lists = append(names, token.(string)), append(lists, list)
+	}
+	return names, lists, nil
+}
+
+// jsonRows decodes a row object or an array of them.
+func (this *fixtureDecoder) jsonRows(data json.RawMessage) ([]map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return this.valueRows(this.jsonValue("", value))
+}
+
+// jsonValue converts the numbers of the decoded value, the column is the key of the map holding the value.
+// The numbers of the numeric text columns are kept as their text, the others become int or float64 as in YAML.
+func (this *fixtureDecoder) jsonValue(column string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = this.jsonValue(key, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = this.jsonValue(column, item)
+		}
+	case json.Number:
+		if _, find := this.numericText[this.table][column]; find {
+			return v.String()
+		}
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
@@ -0,0 +1,28 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestJSONFixtures(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users.json":   `[{"id": 1, "score": 1.5, "settings": {"theme": "dark"}}, {"id": 2, "score": null, "settings": null}]`,
+		"catalog.json": `{"tags": [{"id": 7, "name": "go"}], "posts": [{"id": 3, "tag_id": 7}]}`,
+	})
+	f := NewFixturer("", "", dir, "test", "")
+
+	for table, want := range map[string]string{
+		"users": "[map[id:1 score:1.5 settings:{\"theme\":\"dark\"}] map[id:2 score:<nil> settings:<nil>]]",
+		"tags":  "[map[id:7 name:go]]",
+		"posts": "[map[id:3 tag_id:7]]",
+	} {
+		query, args, err := f.BuildSQL(table)
+		if err != nil {
+			t.Fatalf("%s: %v", table, err)
+		}
+		if got := fmt.Sprint(insertedRows(query, args)); got != want {
+			t.Errorf("%s: got rows %s, want %s", table, got, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package fixturer
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// KeptDatabaseMarkerTable is created in the database preserved after a failed import.
+const KeptDatabaseMarkerTable = "fixturer_kept_database"
+
+// markDatabaseKept records the import failure in the marker table, so the next RecreateDatabase keeps the database.
+func (this *Fixturer) markDatabaseKept(importErr error) {
+	_, err := this.db.Exec("CREATE TABLE IF NOT EXISTS " + this.quoteIdentifier(KeptDatabaseMarkerTable) +
+		" (failed_at TIMESTAMP NOT NULL, error TEXT NOT NULL)")
+	if err == nil {
+		_, err = this.db.Exec(this.rebind("INSERT INTO "+this.quoteIdentifier(KeptDatabaseMarkerTable)+
+			" (failed_at, error) VALUES (CURRENT_TIMESTAMP, ?)"), importErr.Error())
+	}
+	if err != nil {
+		this.logger.Log(LogEvent{
+			Level:   LevelWarn,
+			Message: fmt.Sprintf("Can't mark database %s as kept. Origin error: %v", this.dbName, err),
+			Err:     err,
+		})
+		return
+	}
+	this.logger.Log(LogEvent{
+		Level:   LevelInfo,
+		Message: fmt.Sprintf("Database %s is kept for inspection after the failed import", this.dbName),
+		Err:     importErr,
+	})
+}
+
+// checkDatabaseNotKept returns an error if the database was kept after a failed import. The marker table
+// is looked up in the current schema of the database itself, e.g. public of PostgreSQL, as it was created there.
+func (this *Fixturer) checkDatabaseNotKept(server *sql.DB) error {
+	exists, err := this.databaseExistsOn(server)
+	if err != nil || !exists {
+		return err
+	}
+	if err := this.ensureDbConnected(); err != nil {
+		return err
+	}
+	defer this.ensureDbDisconnected()
+
+	tables, err := this.existingTables()
+	if err != nil {
+		return err
+	}
+	if _, find := tables[KeptDatabaseMarkerTable]; find {
+		return fmt.Errorf("database %s is kept after a failed import, drop table %s.%s to recreate it",
+			this.dbName, this.dbName, KeptDatabaseMarkerTable)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package fixturer
+
+import (
+	"log"
+	"time"
+)
+
+// LogLevel is the importance of a LogEvent.
+type LogLevel int
+
+const (
+	// LevelDebug events are emitted per table and per file.
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+)
+
+// LogEvent is a log record of the fixturer. Message is the human readable text,
+// the other fields are set when they apply to the event.
+type LogEvent struct {
+	Level    LogLevel
+	Message  string
+	Phase    ProgressPhase
+	Table    string
+	File     string
+	Duration time.Duration
+	Rows     int
+	Err      error
+}
+
+// Logger receives the log events of the fixturer, e.g. an adapter passing the fields to a slog.Logger.
+type Logger interface {
+	Log(event LogEvent)
+}
+
+// LoggerFunc adapts a function to the Logger interface.
+type LoggerFunc func(event LogEvent)
+
+func (this LoggerFunc) Log(event LogEvent) {
+	this(event)
+}
+
+// stdLogger is the default Logger printing the messages of the events of its level and above
+// with the standard log package, the info and warn events by default.
+type stdLogger struct {
+	level LogLevel
+}
+
+func (this stdLogger) Log(event LogEvent) {
+	if event.Level >= this.level {
+		log.Println(event.Message)
+	}
+}
+
+// WithLogger sets the logger of the fixturer. Default logger prints the info and warn messages with the log package.
+func (this *Fixturer) WithLogger(logger Logger) IFixturer {
+	if logger == nil {
+		logger = stdLogger{level: LevelInfo}
+	}
+	this.logger = logger
+	return this
+}
+
+// WithLogLevel sets the lowest level of the events printed by the default logger, e.g. LevelWarn to silence
+// the recreation of many databases in a loop or LevelDebug to print every step. A logger set by WithLogger
+// receives all the events and filters them itself.
+func (this *Fixturer) WithLogLevel(level LogLevel) IFixturer {
+	if _, ok := this.logger.(stdLogger); ok {
+		this.logger = stdLogger{level: level}
+	}
+	return this
+}
@@ -0,0 +1,159 @@
+package fixturer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFileName is the name of the optional manifest in the fixtures directory.
+// The manifest lists the tables in load order and may override the import settings per table:
+//
+//	unlisted: append # or ignore: what to do with the fixtures not listed in the manifest
+//	tables:
+//	  - table: users
+//	  - table: orders
+//	    insert_mode: ignore # insert, ignore or upsert
+//	    skip_truncate: true
+//	    batch_size: 500
+//	    clear: delete # truncate, delete or recreate, see ClearStrategy
+const ManifestFileName = "fixtures.yml"
+
+const (
+	manifestUnlistedAppend = "append"
+	manifestUnlistedIgnore = "ignore"
+
+	insertModeInsert = "insert"
+	insertModeIgnore = "ignore"
+	insertModeUpsert = "upsert"
+)
+
+// tableOptions overrides the import settings for a single table.
+type tableOptions struct {
+	insertIgnore bool
+	upsert       bool
+	skipTruncate bool
+	batchSize    int
+	clear        string
+}
+
+type manifest struct {
+	Unlisted string          `yaml:"unlisted"`
+	Tables   []manifestTable `yaml:"tables"`
+}
+
+type manifestTable struct {
+	Table        string `yaml:"table"`
+	InsertMode   string `yaml:"insert_mode"`
+	SkipTruncate bool   `yaml:"skip_truncate"`
+	BatchSize    int    `yaml:"batch_size"`
+	Clear        string `yaml:"clear"`
+}
+
+func (this *Fixturer) tableOptions(table string) tableOptions {
+	return this.tablesOptions[table]
+}
+
+// applyManifest reads the manifest of the fixtures directory, if any, applies its per-table settings
+// and returns the fixture files to load.
+func (this *Fixturer) applyManifest(dir string, files []fixtureFile) ([]fixtureFile, error) {
+	this.manifest = nil
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(m); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", ManifestFileName, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFileName, err)
+	}
+
+	for _, t := range m.Tables {
+		this.tablesOptions[t.Table] = tableOptions{
+			insertIgnore: t.InsertMode == insertModeIgnore,
+			upsert:       t.InsertMode == insertModeUpsert,
+			skipTruncate: t.SkipTruncate,
+			batchSize:    t.BatchSize,
+			clear:        t.Clear,
+		}
+	}
+	this.manifest = m
+
+	if m.Unlisted != manifestUnlistedIgnore {
+		return files, nil
+	}
+	listed := m.tablesSet()
+	var resultSlice []fixtureFile
+	for _, file := range files {
+		if _, find := listed[file.tableName()]; find {
+			resultSlice = append(resultSlice, file)
+		}
+	}
+	return resultSlice, nil
+}
+
+func (this *manifest) validate() error {
+	switch this.Unlisted {
+	case "", manifestUnlistedAppend, manifestUnlistedIgnore:
+	default:
+		return fmt.Errorf("unknown unlisted mode %q", this.Unlisted)
+	}
+	for _, t := range this.Tables {
+		if t.Table == "" {
+			return fmt.Errorf("table name is missing")
+		}
+		switch t.InsertMode {
+		case "", insertModeInsert, insertModeIgnore, insertModeUpsert:
+		default:
+			return fmt.Errorf("unknown insert mode %q of table %s", t.InsertMode, t.Table)
+		}
+		if _, find := clearStrategyNames[t.Clear]; t.Clear != "" && !find {
+			return fmt.Errorf("unknown clear strategy %q of table %s", t.Clear, t.Table)
+		}
+	}
+	return nil
+}
+
+func (this *manifest) tablesSet() map[string]struct{} {
+	tables := make(map[string]struct{}, len(this.Tables))
+	for _, t := range this.Tables {
+		tables[t.Table] = struct{}{}
+	}
+	return tables
+}
+
+// sortTables orders the tables as the manifest lists them followed by the unlisted ones.
+func (this *manifest) sortTables(tables []string) []string {
+	present := make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		present[table] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(tables))
+	for _, t := range this.Tables {
+		if _, find := present[t.Table]; find {
+			sorted = append(sorted, t.Table)
+			delete(present, t.Table)
+		}
+	}
+	for _, table := range tables {
+		if _, find := present[table]; find {
+			sorted = append(sorted, table)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,32 @@
+package fixturer
+
+import "testing"
+
+func TestManifestTableSettings(t *testing.T) {
+	db, fake := openFakeDB(t, respondTables("users", "orders", "notes"))
+	dir := writeFixtures(t, map[string]string{
+		ManifestFileName: "unlisted: ignore\ntables:\n" +
+			"  - table: orders\n    insert_mode: ignore\n    skip_truncate: true\n" +
+			"  - table: users\n    batch_size: 1\n",
+		"users.yml":  "- id: 1\n- id: 2\n",
+		"orders.yml": "- id: 3\n",
+		"notes.yml":  "- id: 4\n",
+	})
+	importFakeFixtures(t, db, dir)
+
+	if inserts := fake.executed("INSERT IGNORE INTO `orders`"); len(inserts) != 1 {
+		t.Errorf("got %d insert ignores into orders, want 1", len(inserts))
+	}
+	if truncates := fake.executed("TRUNCATE `orders`"); len(truncates) != 0 {
+		t.Errorf("got orders truncated, want it skipped")
+	}
+	if truncates := fake.executed("TRUNCATE `users`"); len(truncates) != 1 {
+		t.Errorf("got %d truncates of users, want 1", len(truncates))
+	}
+	if inserts := fake.executed("INSERT INTO `users`"); len(inserts) != 2 {
+		t.Errorf("got %d inserts into users, want 2 batches of a row", len(inserts))
+	}
+	if len(fake.executed("TRUNCATE `notes`")) != 0 || insertedArgs(fake, "`notes`") != "" {
+		t.Errorf("got the unlisted notes loaded, want them ignored")
+	}
+}
@@ -0,0 +1,100 @@
+package fixturer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// MissingColumns is the way the rows of a table omitting some of the columns of the other rows are inserted.
+type MissingColumns int
+
+const (
+	// MissingColumnsGroup inserts the rows of every column set with their own inserts, so the omitted columns
+	// get their defaults. The rows of a column set keep their order, the column sets are inserted in the order
+	// of their first rows.
+	MissingColumnsGroup MissingColumns = iota
+	// MissingColumnsDefault inserts all the rows together with DEFAULT for the omitted columns. SQLite does not
+	// support DEFAULT in the inserted values.
+	MissingColumnsDefault
+	// MissingColumnsNull inserts all the rows together with NULL for the omitted columns.
+	MissingColumnsNull
+)
+
+// WithMissingColumns sets the way the rows omitting some columns are inserted, default is MissingColumnsGroup.
+// The bulk load (see WithBulkLoad) of such rows needs MissingColumnsNull.
+func (this *Fixturer) WithMissingColumns(mode MissingColumns) IFixturer {
+	this.missingColumns = mode
+	this.dropCaches()
+	return this
+}
+
+// columnDefault is the !default value.
+type columnDefault struct{}
+
+// applyDefaults removes the !default columns from the row, so they are inserted as the omitted ones,
+// unless the omitted columns are inserted as NULL. The remaining !default values are inserted as DEFAULT.
+func (this *Fixturer) applyDefaults(row map[string]interface{}) {
+	if this.missingColumns == MissingColumnsNull {
+		return
+	}
+	for column, value := range row {
+		if _, ok := value.(columnDefault); ok {
+			delete(row, column)
+		}
+	}
+}
+
+// hasDefaults reports whether some of the rows have the !default values.
+func hasDefaults(rows []map[string]interface{}) bool {
+	for _, row := range rows {
+		for _, value := range row {
+			if _, ok := value.(columnDefault); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// missingValue returns the value of the column omitted by the row, nil if the columns are grouped.
+func (this *Fixturer) missingValue() interface{} {
+	if this.missingColumns == MissingColumnsDefault {
+		return squirrel.Expr("DEFAULT")
+	}
+	return nil
+}
+
+// groupRowsByColumns splits the rows by their column sets in the order of the first rows of the sets.
+func groupRowsByColumns(rows []map[string]interface{}) (columns [][]string, groups [][]map[string]interface{}) {
+	index := map[string]int{}
+	for _, row := range rows {
+		rowColumns := make([]string, 0, len(row))
+		for column := range row {
+			rowColumns = append(rowColumns, column)
+		}
+		sort.Strings(rowColumns)
+		key := strings.Join(rowColumns, "\x00")
+
+		i, find := index[key]
+		if !find {
+			i = len(groups)
+			index[key] = i
+			columns = append(columns, rowColumns)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return columns, groups
+}
+
+// sparseRows reports whether some of the rows omit some of the columns.
+func sparseRows(rows []map[string]interface{}, columns []string) bool {
+	for _, row := range rows {
+		if len(row) != len(columns) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package fixturer
+
+// Option configures the fixturer made by NewFixturerWithOptions. The chainable setters of IFixturer
+// may be applied to it afterwards.
+type Option func(*Fixturer)
+
+// NewFixturerWithOptions creates the fixturer of the options, e.g.
+//
+//	NewFixturerWithOptions(WithDSN("root:pass@tcp(127.0.0.1:3306)/"), WithDatabase("test"),
+//		WithSchemaFile("schema.sql"), WithFixturesDir("fixtures"))
+//
+// The defaults are the ones of NewFixturer.
+func NewFixturerWithOptions(opts ...Option) IFixturer {
+	f := NewFixturer("", "", "", "", "").(*Fixturer)
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// WithDSN sets the DSN of the server without the database, the dbConf of NewFixturer.
+func WithDSN(dsn string) Option {
+	return func(f *Fixturer) {
+		f.dbConf = dsn
+	}
+}
+
+// WithDatabase sets the name of the fixtures database.
+func WithDatabase(name string) Option {
+	return func(f *Fixturer) {
+		f.dbName = name
+	}
+}
+
+// WithDSNParams sets the params of the DSN, the dbParams of NewFixturer.
+func WithDSNParams(params string) Option {
+	return func(f *Fixturer) {
+		f.dbParams = params
+	}
+}
+
+// WithSchemaFile sets the schema file, or the directory of the schema files, loaded by LoadDbSchema.
+func WithSchemaFile(path string) Option {
+	return func(f *Fixturer) {
+		f.schema = path
+	}
+}
+
+// WithFixturesDir sets the directory of the fixture files.
+func WithFixturesDir(dir string) Option {
+	return func(f *Fixturer) {
+		f.fixturesPathYml = dir
+	}
+}
+
+// WithRecreate sets whether RecreateDatabaseWithSchemaAndImportFixtures recreates the database, see SetRecreateDatabase.
+func WithRecreate(recreate bool) Option {
+	return func(f *Fixturer) {
+		f.SetRecreateDatabase(recreate)
+	}
+}
+
+// WithGoroutines sets the count of the insert goroutines, see SetInsertGoroutinesCnt.
+func WithGoroutines(n int) Option {
+	return func(f *Fixturer) {
+		f.SetInsertGoroutinesCnt(n)
+	}
+}
@@ -0,0 +1,54 @@
+package fixturer
+
+import "sync"
+
+// ProgressPhase is the stage of the import a ProgressEvent belongs to.
+type ProgressPhase string
+
+const (
+	PhaseParsing    ProgressPhase = "parsing"
+	PhaseTruncating ProgressPhase = "truncating"
+	PhaseInserting  ProgressPhase = "inserting"
+
+	// The phases below are only set on the log events.
+	PhaseRecreating  ProgressPhase = "recreating"
+	PhaseSchema      ProgressPhase = "schema"
+	PhasePreImport   ProgressPhase = "pre-import"
+	PhaseSQLFixtures ProgressPhase = "sql-fixtures"
+	PhasePostImport  ProgressPhase = "post-import"
+)
+
+// ProgressEvent is emitted once a table passed a phase of the import.
+// Done of Total tables have passed the phase so far, Rows is the rows count of the table.
+type ProgressEvent struct {
+	Phase ProgressPhase
+	Table string
+	Rows  int
+	Done  int
+	Total int
+}
+
+// progressReporter counts the tables of the current phase and serializes the callback calls.
+type progressReporter struct {
+	callback func(ProgressEvent)
+	mutex    sync.Mutex
+	phase    ProgressPhase
+	done     int
+	total    int
+}
+
+func (this *progressReporter) start(phase ProgressPhase, total int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.phase, this.done, this.total = phase, 0, total
+}
+
+func (this *progressReporter) step(table string, rows int) {
+	if this.callback == nil {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.done++
+	this.callback(ProgressEvent{Phase: this.phase, Table: table, Rows: rows, Done: this.done, Total: this.total})
+}
@@ -0,0 +1,168 @@
+package fixturer
+
+import (
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LabelKey is the reserved fixture column naming a row, so other fixtures may reference its key with !ref:
+//
+//	# regions.yml
+//	- _label: east
+//	  country_id: 1
+//	  region_code: E
+//	# offices.yml
+//	- id: 10
+//	  region: !ref east
+//
+// A reference to a table with a single key column is replaced with the key value. A reference to a table
+// with a composite key (see SetKeyColumns) is replaced with all the key columns, so the office above gets
+// country_id and region_code. Labels are unique across the fixtures. The rows of streamed fixtures
+// may reference labels but can't define them.
+//
+// A string value of a dollar and a defined label is a reference as well, e.g. `user_id: $admin`, the other
+// strings starting with a dollar are kept. A labeled row of a table with a single key column may omit the key,
+// it gets the ID derived from the label (see LabelID) like in the Rails fixtures, so the referencing rows know it
+// before the insert and the tables are inserted in the usual order.
+const LabelKey = "_label"
+
+// labelIDMax bounds the IDs derived from the labels, they fit any signed integer column.
+const labelIDMax = 1<<30 - 1
+
+// LabelID returns the ID of the labeled row without its key, the CRC-32 of the label modulo 2^30-1.
+func LabelID(label string) int {
+	return int(crc32.ChecksumIEEE([]byte(label)) % labelIDMax)
+}
+
+// dollarReferenceRegexp matches the `$admin` reference to a label.
+var dollarReferenceRegexp = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// DefaultKeyColumn is the key column of the tables without SetKeyColumns.
+const DefaultKeyColumn = "id"
+
+// reference is a !ref value waiting to be resolved.
+type reference struct {
+	label string
+}
+
+// labeledRow is a row registered by its label.
+type labeledRow struct {
+	table string
+	file  string
+	key   map[string]interface{}
+}
+
+// SetKeyColumns sets the key columns of the table used to resolve the references to its labeled rows.
+// Default is DefaultKeyColumn.
+func (this *Fixturer) SetKeyColumns(table string, columns ...string) IFixturer {
+	this.keyColumns[table] = columns
+	this.dropCaches()
+	return this
+}
+
+// forgetLabels removes the labels registered from the table of the fixture file.
+func (this *Fixturer) forgetLabels(file, table string) {
+	for name, labeled := range this.labels {
+		if labeled.file == file && labeled.table == table {
+			delete(this.labels, name)
+		}
+	}
+}
+
+func (this *Fixturer) tableKeyColumns(table string) []string {
+	if columns, find := this.keyColumns[table]; find && len(columns) > 0 {
+		return columns
+	}
+	return []string{DefaultKeyColumn}
+}
+
+// registerLabels takes the labels out of the rows and remembers the keys of the labeled rows.
+// The labeled rows without the key get the IDs derived from the labels.
+func (this *Fixturer) registerLabels(queries []*insertQuery) error {
+	// ids are the labels of the derived IDs by the table.
+	ids := map[string]map[int]string{}
+	for _, query := range queries {
+		for i, row := range query.rows {
+			label, find := row[LabelKey]
+			if !find {
+				continue
+			}
+			delete(row, LabelKey)
+
+			name := fmt.Sprint(label)
+			if registered, find := this.labels[name]; find {
+				return queryError(PhaseParsing, query, fmt.Errorf("label %q is defined in %s too", name, registered.file))
+			}
+
+			key := map[string]interface{}{}
+			keyColumns := this.tableKeyColumns(query.table)
+			if _, find := row[keyColumns[0]]; !find && len(keyColumns) == 1 {
+				id := LabelID(name)
+				if other, find := ids[query.table][id]; find {
+					return queryError(PhaseParsing, query,
+						fmt.Errorf("labels %q and %q derive the same ID %d, set the key of one of them", other, name, id))
+				}
+				if ids[query.table] == nil {
+					ids[query.table] = map[int]string{}
+				}
+				ids[query.table][id] = name
+				row[keyColumns[0]] = id
+			}
+			for _, column := range keyColumns {
+				value, find := row[column]
+				if !find {
+					return queryError(PhaseParsing, query, fmt.Errorf("row %d: label %q has no value of key column %s", i, name, column))
+				}
+				key[column] = value
+			}
+			this.labels[name] = labeledRow{table: query.table, file: query.file, key: key}
+		}
+	}
+	return nil
+}
+
+// resolveReferences replaces the !ref and $label values of the row with the keys of the labeled rows.
+func (this *Fixturer) resolveReferences(row map[string]interface{}) error {
+	var columns []string
+	for column, value := range row {
+		switch v := value.(type) {
+		case reference:
+			columns = append(columns, column)
+		case string:
+			if match := dollarReferenceRegexp.FindStringSubmatch(v); match != nil {
+				if _, find := this.labels[match[1]]; find {
+					row[column] = reference{label: match[1]}
+					columns = append(columns, column)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		ref := row[column].(reference)
+		labeled, find := this.labels[ref.label]
+		if !find {
+			return fmt.Errorf("column %s references unknown label %q", column, ref.label)
+		}
+
+		keyColumns := this.tableKeyColumns(labeled.table)
+		if len(keyColumns) == 1 {
+			row[column] = labeled.key[keyColumns[0]]
+			continue
+		}
+
+		delete(row, column)
+		for _, keyColumn := range keyColumns {
+			if _, find := row[keyColumn]; find {
+				return fmt.Errorf("column %s references %q whose key columns %s are set explicitly",
+					column, ref.label, strings.Join(keyColumns, ", "))
+			}
+			row[keyColumn] = labeled.key[keyColumn]
+		}
+	}
+	return nil
+}
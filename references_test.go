@@ -0,0 +1,30 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompositeKeyReference(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"regions.yml": "- _label: east\n  country_id: 1\n  region_code: E\n",
+		"offices.yml": "- id: 10\n  region: !ref east\n",
+	})
+	f := NewFixturer("", "", dir, "test", "").SetKeyColumns("regions", "country_id", "region_code")
+
+	query, args, err := f.BuildSQL("offices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := insertedRows(query, args)
+	if got := fmt.Sprint(rows); got != "[map[country_id:1 id:10 region_code:E]]" {
+		t.Errorf("got rows %s, want the key columns of the referenced region", got)
+	}
+}
+
+func TestUnknownLabelReference(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{"offices.yml": "- id: 10\n  region: !ref west\n"})
+	if _, _, err := NewFixturer("", "", dir, "test", "").BuildSQL("offices"); err == nil {
+		t.Error("got no error for the reference to an unknown label")
+	}
+}
@@ -0,0 +1,96 @@
+package fixturer
+
+import (
+	"fmt"
+	"os"
+)
+
+// RefreshTable reads the fixture of the table again, clears the table and inserts the fixture, leaving the other
+// tables intact. It bypasses the fixtures cache for the file and updates the cache, so a later Reset loads
+// the refreshed rows. It uses the open connection or connects for the call. The .sql fixtures are not executed.
+func (this *Fixturer) RefreshTable(table string) error {
+	f, err := this.tableFixtureFile(table)
+	if err != nil {
+		return err
+	}
+
+	if this.db == nil {
+		if err := this.ensureDbConnected(); err != nil {
+			return err
+		}
+		defer this.ensureDbDisconnected()
+	}
+
+	query := &insertQuery{file: f.path, table: table, streamed: true}
+	if !this.streaming || f.data != nil || f.Size() < StreamingFileSizeThreshold {
+		if query, err = this.parseTableFixture(f, table); err != nil {
+			return err
+		}
+	} else if err := this.checkStreamable(table); err != nil {
+		return err
+	}
+
+	key := f.path
+	if query.multiTable {
+		key += "#" + table
+	}
+	this.cache().queries[key] = query
+
+	if err := this.loadTables([][]string{{table}}); err != nil {
+		return err
+	}
+	this.setLastImport([]string{table}, query.rowsCount())
+	return nil
+}
+
+// tableFixtureFile finds the fixture file of the table, either named after the table
+// or a multi-table fixture parsed before.
+func (this *Fixturer) tableFixtureFile(table string) (fixtureFile, error) {
+	files, err := this.fixtureFiles()
+	if err != nil {
+		return fixtureFile{}, err
+	}
+	for _, f := range files {
+		if f.tableName() == table {
+			return f, nil
+		}
+	}
+
+	for _, query := range this.cache().queries {
+		if query.table != table || !query.multiTable {
+			continue
+		}
+		// The listed file keeps the schema of its subdirectory, if any.
+		for _, f := range files {
+			if f.path == query.file {
+				return f, nil
+			}
+		}
+		info, err := os.Stat(query.file)
+		if err != nil {
+			return fixtureFile{}, err
+		}
+		return fixtureFile{FileInfo: info, path: query.file}, nil
+	}
+	return fixtureFile{}, fmt.Errorf("no fixture for table %s in %s", table, this.fixturesSource())
+}
+
+// parseTableFixture parses the fixture file and builds the inserts of the table.
+func (this *Fixturer) parseTableFixture(f fixtureFile, table string) (*insertQuery, error) {
+	tables, err := this.parseFixtureTables(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		if t.table != table {
+			continue
+		}
+		query := &insertQuery{file: f.path, table: table, rows: t.rows, multiTable: t.multiTable}
+		this.forgetLabels(f.path, table)
+		if err := this.buildParsedInserts([]*insertQuery{query}); err != nil {
+			return nil, err
+		}
+		return query, nil
+	}
+	return nil, fmt.Errorf("fixture %s has no table %s", f.path, table)
+}
@@ -0,0 +1,83 @@
+package fixturer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RepeatKey is the reserved fixture column making the row a template of the given number of rows, e.g.
+//
+//	# users.yml
+//	- _repeat: 1000
+//	  _label: "user_{{ .Number }}"
+//	  email: "user{{ .Number }}@example.com"
+//	  name: "{{ fake.Name }}"
+//
+// The string values are executed as text/template with .Index from 0 and .Number from 1, along with the helpers
+// of WithTemplates, which is not required. With WithTemplates the file is executed first, and only a bare
+// {{ .Index }} or {{ .Number }} is kept for the rows, so the other actions of a row meant to run per copy,
+// e.g. fake.Name, must be escaped as {{ "{{ fake.Name }}" }}. The rows of streamed fixtures are repeated as well.
+const RepeatKey = "_repeat"
+
+// repeatData is the data of the repeated row templates.
+type repeatData struct {
+	Index  int
+	Number int
+}
+
+// repeatRows replaces the rows with RepeatKey with their copies.
+func (this *Fixturer) repeatRows(path string, rows []map[string]interface{}) ([]map[string]interface{}, error) {
+	var result []map[string]interface{}
+	for i, row := range rows {
+		value, find := row[RepeatKey]
+		if !find {
+			if result != nil {
+				result = append(result, row)
+			}
+			continue
+		}
+		if result == nil {
+			result = append(make([]map[string]interface{}, 0, len(rows)), rows[:i]...)
+		}
+		count, ok := value.(int)
+		if !ok || count < 0 {
+			return nil, fmt.Errorf("row %d: %s must be a non-negative integer, got %v", i, RepeatKey, value)
+		}
+		delete(row, RepeatKey)
+
+		templates := map[string]*template.Template{}
+		funcs := templateFuncs(this.newFaker(path))
+		for column, value := range row {
+			s, ok := value.(string)
+			if !ok || !strings.Contains(s, "{{") {
+				continue
+			}
+			tmpl, err := template.New(column).Funcs(funcs).Funcs(this.templateFuncs).Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: column %s: %w", i, column, err)
+			}
+			templates[column] = tmpl
+		}
+
+		for index := 0; index < count; index++ {
+			copied := make(map[string]interface{}, len(row))
+			for column, value := range row {
+				copied[column] = value
+			}
+			for column, tmpl := range templates {
+				var text bytes.Buffer
+				if err := tmpl.Execute(&text, repeatData{Index: index, Number: index + 1}); err != nil {
+					return nil, fmt.Errorf("row %d: column %s: %w", i, column, err)
+				}
+				copied[column] = text.String()
+			}
+			result = append(result, copied)
+		}
+	}
+	if result == nil {
+		return rows, nil
+	}
+	return result, nil
+}
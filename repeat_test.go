@@ -0,0 +1,28 @@
+package fixturer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRepeatRows(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "- id: 100\n  email: admin@example.com\n" +
+			"- _repeat: 3\n  id: \"{{ .Number }}\"\n  email: \"user{{ .Index }}@example.com\"\n" +
+			"- _repeat: 0\n  id: 200\n  email: never@example.com\n",
+	})
+	query, args, err := NewFixturer("", "", dir, "test", "").BuildSQL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[map[email:admin@example.com id:100] map[email:user0@example.com id:1] " +
+		"map[email:user1@example.com id:2] map[email:user2@example.com id:3]]"
+	if got := fmt.Sprint(insertedRows(query, args)); got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	dir = writeFixtures(t, map[string]string{"users.yml": "- _repeat: many\n  id: 1\n"})
+	if _, _, err := NewFixturer("", "", dir, "test", "").WithLogger(LoggerFunc(func(LogEvent) {})).BuildSQL("users"); err == nil {
+		t.Error("got no error for the non-integer _repeat")
+	}
+}
@@ -0,0 +1,225 @@
+package fixturer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Source downloads the fixture files, or the fixture archives, under the prefix of an S3 bucket.
+// The requests are signed with AWS Signature Version 4 unless there are no credentials, e.g. for a public bucket.
+type S3Source struct {
+	Bucket string
+	// Prefix selects the objects, e.g. "shop/fixtures/".
+	Prefix string
+	// Region defaults to AWS_REGION, then to us-east-1.
+	Region string
+	// Endpoint is the URL of an S3 compatible storage, e.g. http://127.0.0.1:9000 of MinIO. The default is the AWS
+	// endpoint of the region. The bucket is always addressed in the path.
+	Endpoint string
+	// The credentials default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Client makes the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (this S3Source) Name() string {
+	return "s3://" + this.Bucket + "/" + this.Prefix
+}
+
+// Fetch lists the objects under the prefix and downloads the fixtures and the archives, named relative to the prefix.
+func (this S3Source) Fetch(ctx context.Context) ([]SourceFile, error) {
+	this = this.withDefaults()
+	keys, err := this.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []SourceFile
+	for _, key := range keys {
+		if fixtureExt(key) == "" && !isArchive(key) {
+			continue
+		}
+		req, err := this.request(ctx, "/"+this.Bucket+"/"+key, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fetchHTTP(this.Client, req)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, SourceFile{Name: strings.TrimPrefix(key, this.Prefix), Data: data})
+	}
+	return files, nil
+}
+
+func (this S3Source) withDefaults() S3Source {
+	if this.Region == "" {
+		this.Region = os.Getenv("AWS_REGION")
+	}
+	if this.Region == "" {
+		this.Region = "us-east-1"
+	}
+	if this.Endpoint == "" {
+		this.Endpoint = "https://s3." + this.Region + ".amazonaws.com"
+	}
+	if this.AccessKeyID == "" {
+		this.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		this.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		this.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	return this
+}
+
+// s3ListResult is the response of ListObjectsV2.
+type s3ListResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// list returns the keys of the objects under the prefix with ListObjectsV2.
+func (this S3Source) list(ctx context.Context) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {this.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := this.request(ctx, "/"+this.Bucket, query)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fetchHTTP(this.Client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListResult
+		if err := xml.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("list %s: %w", this.Name(), err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// request makes the signed GET request of the path of the endpoint.
+func (this S3Source) request(ctx context.Context, objectPath string, query url.Values) (*http.Request, error) {
+	endpoint, err := url.Parse(this.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + objectPath
+	endpoint.RawPath = s3Escape(endpoint.Path, false)
+	endpoint.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if this.AccessKeyID != "" {
+		this.sign(req, time.Now().UTC())
+	}
+	return req, nil
+}
+
+// emptySHA256 is the hex SHA-256 of the empty payload of the GET requests.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds the AWS Signature Version 4 headers to the request.
+func (this S3Source) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if this.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", this.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(req.Header.Get(key))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, emptySHA256,
+	}, "\n")
+	scope := date + "/" + this.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + this.SecretAccessKey)
+	for _, part := range []string{date, this.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		this.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery encodes the query sorted by the keys as the signature needs it.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything except the unreserved characters, and the slashes unless encodeSlash.
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			c == '/' && !encodeSlash {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package fixturer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	createTableRegexp = regexp.MustCompile(`(?is)^CREATE\s+(?:TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)`)
+	createViewRegexp  = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:ALGORITHM\s*=\s*\w+\s+)?` +
+		`(?:DEFINER\s*=\s*\S+\s+)?(?:SQL\s+SECURITY\s+\w+\s+)?VIEW\s+([^\s(]+)`)
+	// requiresRegexp matches the `-- requires: users, roles` comment declaring the tables
+	// a schema file needs to be created by the other files first.
+	requiresRegexp = regexp.MustCompile(`(?im)^\s*--\s*requires:(.*)$`)
+)
+
+// implicitCommitRegexp matches the DDL statements committed implicitly regardless of the transaction.
+var implicitCommitRegexp = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP|RENAME|TRUNCATE)\s`)
+
+// PartialSchemaError is returned by LoadDbSchema when a statement failed after some DDL statements
+// had been committed implicitly, so the database is left with the Applied statements only.
+type PartialSchemaError struct {
+	Applied []string
+	Err     error
+}
+
+func (this *PartialSchemaError) Error() string {
+	return fmt.Sprintf("schema is partially applied, %d DDL statements were committed before the failure (%s): %v",
+		len(this.Applied), strings.Join(this.Applied, "; "), this.Err)
+}
+
+func (this *PartialSchemaError) Unwrap() error {
+	return this.Err
+}
+
+func isImplicitCommitStatement(query string) bool {
+	return implicitCommitRegexp.MatchString(strings.TrimSpace(stripLeadingSqlComments(query)))
+}
+
+// schemaFailure handles the failed schema load. The DDL statements applied before the failure can't be rolled back,
+// so the database is recreated empty when the recreation is enabled, otherwise *PartialSchemaError reports them.
+func (this *Fixturer) schemaFailure(applied []string, err error) error {
+	if len(applied) == 0 {
+		return err
+	}
+	if !this.recreateDatabase || this.ensureSchema {
+		return &PartialSchemaError{Applied: applied, Err: err}
+	}
+
+	this.logger.Log(LogEvent{
+		Level:   LevelWarn,
+		Message: fmt.Sprintf("Schema load failed after %d DDL statements, recreate database %s", len(applied), this.dbName),
+		Phase:   PhaseSchema,
+		Err:     err,
+	})
+	if recreateErr := this.RecreateDatabase(); recreateErr != nil {
+		return &PartialSchemaError{Applied: applied, Err: fmt.Errorf("%w; recreate database: %v", err, recreateErr)}
+	}
+	return fmt.Errorf("%w (database %s is recreated empty)", err, this.dbName)
+}
+
+// schemaFile is a schema file with the tables it creates and requires.
+type schemaFile struct {
+	path     string
+	script   string
+	creates  []string
+	requires []string
+}
+
+// WithSchemaFiles sets the schema files loaded by LoadDbSchema in the given order instead of the schema path.
+// The schema path may also be a directory of *.sql and *.sql.gz files loaded in the name order. Either way a file declaring
+// `-- requires: users, roles` is loaded after the files creating those tables or views.
+func (this *Fixturer) WithSchemaFiles(files ...string) IFixturer {
+	this.schemaFiles = files
+	return this
+}
+
+// readSchemaFiles reads the schema files in the load order.
+func (this *Fixturer) readSchemaFiles() ([]schemaFile, error) {
+	paths := this.schemaFiles
+	if len(paths) == 0 {
+		info, err := os.Stat(this.schema)
+		if err != nil {
+			return nil, err
+		}
+		paths = []string{this.schema}
+		if info.IsDir() {
+			if paths, err = filepath.Glob(filepath.Join(this.schema, "*.sql")); err != nil {
+				return nil, err
+			}
+			compressed, err := filepath.Glob(filepath.Join(this.schema, "*.sql"+gzipExt))
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, compressed...)
+			sort.Strings(paths)
+		}
+	}
+
+	files := make(map[string]schemaFile, len(paths))
+	creators := map[string]string{}
+	for _, path := range paths {
+		script, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file := schemaFile{path: path, script: string(script)}
+		for _, query := range splitSqlStatements(file.script) {
+			if target := schemaStatementTarget(query); target != "" {
+				file.creates = append(file.creates, target)
+				creators[target] = path
+			}
+		}
+		for _, match := range requiresRegexp.FindAllStringSubmatch(file.script, -1) {
+			for _, table := range strings.Split(match[1], ",") {
+				if table = strings.Trim(strings.TrimSpace(table), "`"); table != "" {
+					file.requires = append(file.requires, table)
+				}
+			}
+		}
+		files[path] = file
+	}
+
+	// Required tables no file creates are expected to exist already.
+	references := make(map[string][]string, len(files))
+	for path, file := range files {
+		for _, table := range file.requires {
+			if creator, find := creators[table]; find {
+				references[path] = append(references[path], creator)
+			}
+		}
+	}
+	sorted, err := sortTablesByReferences(paths, references)
+	if err != nil {
+		return nil, fmt.Errorf("schema files requirements: %w", err)
+	}
+
+	result := make([]schemaFile, 0, len(sorted))
+	for _, path := range sorted {
+		result = append(result, files[path])
+	}
+	return result, nil
+}
+
+// WithEnsureSchema makes LoadDbSchema skip the CREATE TABLE and CREATE VIEW statements of the tables and views
+// which already exist, so the schema may be loaded into an existing database. The other statements are executed as usual.
+func (this *Fixturer) WithEnsureSchema(enabled bool) IFixturer {
+	this.ensureSchema = enabled
+	return this
+}
+
+// schemaStatementTarget returns the table or view created by the statement, or an empty string.
+func schemaStatementTarget(query string) string {
+	query = strings.TrimSpace(stripLeadingSqlComments(query))
+	for _, re := range []*regexp.Regexp{createTableRegexp, createViewRegexp} {
+		if match := re.FindStringSubmatch(query); match != nil {
+			name := match[1]
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			return strings.Trim(name, "`\"")
+		}
+	}
+	return ""
+}
+
+// stripLeadingSqlComments removes the comment lines the statement starts with.
+func stripLeadingSqlComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"), strings.HasPrefix(query, "#"):
+			i := strings.Index(query, "\n")
+			if i < 0 {
+				return ""
+			}
+			query = query[i+1:]
+		case strings.HasPrefix(query, "/*") && !strings.HasPrefix(query, "/*!"):
+			i := strings.Index(query, "*/")
+			if i < 0 {
+				return ""
+			}
+			query = query[i+2:]
+		default:
+			return query
+		}
+	}
+}
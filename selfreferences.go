@@ -0,0 +1,116 @@
+package fixturer
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SelfReferencesDialect is a Dialect reading the foreign keys of the tables referencing themselves,
+// e.g. categories.parent_id. While the foreign key checks are on (see SetDisableForeignKeyChecks),
+// the rows of such a table are inserted parents first, so the fixture may list them in any order.
+type SelfReferencesDialect interface {
+	Dialect
+	// SelfReferencesQuery returns the table, the column and the referenced column of the foreign keys
+	// referencing their own table.
+	SelfReferencesQuery() string
+}
+
+// selfReference is a column referencing the column of its own table.
+type selfReference struct {
+	column     string
+	referenced string
+}
+
+// discoverSelfReferences reads the self-referencing columns of the database tables, if connected,
+// the foreign key checks are on and the dialect supports it.
+func (this *Fixturer) discoverSelfReferences() error {
+	this.selfReferences = map[string][]selfReference{}
+	d, ok := this.dialect.(SelfReferencesDialect)
+	q := this.introspector()
+	if !ok || q == nil || this.disableForeignKeys {
+		return nil
+	}
+	rows, err := q.Query(d.SelfReferencesQuery())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, column string
+		var referenced sql.NullString
+		if err := rows.Scan(&table, &column, &referenced); err != nil {
+			return err
+		}
+		// SQLite leaves the referenced column empty for the primary key.
+		if !referenced.Valid {
+			continue
+		}
+		this.selfReferences[table] = append(this.selfReferences[table], selfReference{column: column, referenced: referenced.String})
+	}
+	return rows.Err()
+}
+
+// sortRowsBySelfReferences orders the rows of the table so every row goes after the rows it references
+// through the self-referencing columns. Rows of the same level keep their order, the rows of a cycle
+// are left at the end, so the insert reports the broken reference.
+func (this *Fixturer) sortRowsBySelfReferences(query *insertQuery) {
+	references := this.selfReferences[query.table]
+	if len(references) == 0 || len(query.rows) < 2 {
+		return
+	}
+
+	// The keys are compared as the text, so "5" references 5.
+	indexes := make([]map[string]int, len(references))
+	for i, reference := range references {
+		indexes[i] = map[string]int{}
+		for j, row := range query.rows {
+			if value, find := row[reference.referenced]; find && value != nil {
+				indexes[i][fmt.Sprint(value)] = j
+			}
+		}
+	}
+
+	pending := make([]map[int]struct{}, len(query.rows))
+	for j, row := range query.rows {
+		pending[j] = map[int]struct{}{}
+		for i, reference := range references {
+			value, find := row[reference.column]
+			if !find || value == nil {
+				continue
+			}
+			if parent, find := indexes[i][fmt.Sprint(value)]; find && parent != j {
+				pending[j][parent] = struct{}{}
+			}
+		}
+	}
+
+	sorted := make([]map[string]interface{}, 0, len(query.rows))
+	done := make([]bool, len(query.rows))
+	for len(sorted) < len(query.rows) {
+		var ready []int
+		for j := range query.rows {
+			if !done[j] && len(pending[j]) == 0 {
+				ready = append(ready, j)
+			}
+		}
+		if len(ready) == 0 {
+			for j, row := range query.rows {
+				if !done[j] {
+					sorted = append(sorted, row)
+				}
+			}
+			break
+		}
+		for _, j := range ready {
+			done[j] = true
+			sorted = append(sorted, query.rows[j])
+		}
+		for j := range pending {
+			for _, parent := range ready {
+				delete(pending[j], parent)
+			}
+		}
+	}
+	query.rows = sorted
+}
@@ -0,0 +1,150 @@
+package fixturer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// FixtureSource provides the fixture files from outside of the local file system, e.g. HTTPSource or S3Source.
+type FixtureSource interface {
+	// Name identifies the source in the logs and the errors, e.g. its URL.
+	Name() string
+	// Fetch returns the fixture files. A file named like a .tar.gz, .tgz, .tar or .zip archive is replaced
+	// with its fixture entries.
+	Fetch(ctx context.Context) ([]SourceFile, error)
+}
+
+// SourceFile is a file of a FixtureSource, the table is named after its base name without the extension.
+type SourceFile struct {
+	// Name is the path of the file within the source, e.g. users.yml.
+	Name string
+	Data []byte
+}
+
+// WithFixtureSource loads the fixtures of the source instead of the fixtures directory. The files are fetched
+// once by the first import and kept in memory for the next ones, so they are never streamed, and neither
+// the manifest nor the .sql fixtures are applied. !include is not supported. Nil source restores the fixtures directory.
+func (this *Fixturer) WithFixtureSource(source FixtureSource) IFixturer {
+	this.fixtureSource, this.sourceFiles = source, nil
+	this.dropCache()
+	return this
+}
+
+// fetchSourceFiles returns the fixture files of the source, fetching them unless fetched before.
+func (this *Fixturer) fetchSourceFiles() ([]fixtureFile, error) {
+	if this.sourceFiles != nil {
+		return this.sourceFiles, nil
+	}
+
+	this.logger.Log(LogEvent{Level: LevelInfo, Message: "Fetch fixtures", File: this.fixtureSource.Name()})
+	start := time.Now()
+	fetched, err := this.fixtureSource.Fetch(this.context())
+	if err != nil {
+		return nil, fmt.Errorf("fetch fixtures from %s: %w", this.fixtureSource.Name(), err)
+	}
+
+	files := []fixtureFile{}
+	for _, f := range fetched {
+		name := this.fixtureSource.Name() + "/" + strings.TrimPrefix(path.Clean(f.Name), "/")
+		if isArchive(f.Name) {
+			entries, err := readArchive(name, bytes.NewReader(f.Data), int64(len(f.Data)))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, entries...)
+			continue
+		}
+		if fixtureExt(f.Name) == "" {
+			continue
+		}
+		info := sourceFileInfo{name: path.Base(f.Name), size: int64(len(f.Data))}
+		files = append(files, fixtureFile{FileInfo: info, path: name, data: f.Data})
+	}
+	this.logger.Log(LogEvent{
+		Level:    LevelDebug,
+		Message:  fmt.Sprintf("Fetched %d fixture files", len(files)),
+		File:     this.fixtureSource.Name(),
+		Duration: time.Since(start),
+	})
+	this.sourceFiles = files
+	return files, nil
+}
+
+// sourceFileInfo describes a fetched file.
+type sourceFileInfo struct {
+	name string
+	size int64
+}
+
+func (this sourceFileInfo) Name() string       { return this.name }
+func (this sourceFileInfo) Size() int64        { return this.size }
+func (this sourceFileInfo) Mode() os.FileMode  { return 0o444 }
+func (this sourceFileInfo) ModTime() time.Time { return time.Time{} }
+func (this sourceFileInfo) IsDir() bool        { return false }
+func (this sourceFileInfo) Sys() interface{}   { return nil }
+
+// HTTPSource downloads the fixture files from an HTTP(S) server.
+type HTTPSource struct {
+	// URL is the base URL of the files, e.g. https://fixtures.example.com/shop/.
+	URL string
+	// Names are the paths of the files relative to URL, e.g. users.yml, or of an archive, e.g. fixtures.tar.gz.
+	Names []string
+	// Header is added to the requests, e.g. Authorization.
+	Header http.Header
+	// Client makes the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (this HTTPSource) Name() string {
+	return this.URL
+}
+
+func (this HTTPSource) Fetch(ctx context.Context) ([]SourceFile, error) {
+	files := make([]SourceFile, 0, len(this.Names))
+	for _, name := range this.Names {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(this.URL, "/")+"/"+strings.TrimPrefix(name, "/"), nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range this.Header {
+			req.Header[key] = values
+		}
+		data, err := fetchHTTP(this.Client, req)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, SourceFile{Name: name, Data: data})
+	}
+	return files, nil
+}
+
+// fetchHTTP makes the request and returns the body of the successful response.
+func fetchHTTP(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return nil, fmt.Errorf("GET %s: %s: %s", req.URL.Redacted(), resp.Status, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
@@ -0,0 +1,40 @@
+package fixturer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/shop/users.yml":
+			fmt.Fprint(w, "- id: 1\n  name: Alice\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := HTTPSource{URL: server.URL + "/shop/", Names: []string{"users.yml"}, Header: http.Header{"Authorization": {"Bearer secret"}}}
+	f := NewFixturer("", "", "", "test", "").WithLogger(LoggerFunc(func(LogEvent) {})).WithFixtureSource(source)
+	query, args, err := f.BuildSQL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(insertedRows(query, args)), "[map[id:1 name:Alice]]"; got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	source.Names = []string{"orders.yml"}
+	if _, err := source.Fetch(context.Background()); err == nil {
+		t.Error("got no error for the missing file")
+	}
+}
@@ -0,0 +1,102 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sqlFixtureExt is the extension of the raw SQL fixtures.
+const sqlFixtureExt = ".sql"
+
+// sqlExecer executes the .sql fixtures, the connection or the transaction of ImportFixturesTx.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// execSqlFixtures executes the .sql files of the fixtures directory, with its subdirectories if WithRecursiveFixtures
+// is set, or the ones matching the fixtures glob pattern, in the order of their names after the other fixtures
+// are inserted, e.g. for INSERT ... SELECT or a multi-statement setup. Every file is executed verbatim
+// with a single Exec, so the driver must accept multiple statements, which the default MySQL DSN does
+// (see DefaultDSNParams). Their tables are not cleared before, so a file deleting its rows first keeps the import
+// repeatable. They are executed by ImportFixtures, ImportFixturesTx and Reset, but not by RefreshTable.
+// The archive entries and the files of WithFixturesFS or WithFixtureSource are not executed.
+func (this *Fixturer) execSqlFixtures(execer sqlExecer) error {
+	paths, err := this.sqlFixtureFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		this.logger.Log(LogEvent{Level: LevelInfo, Message: fmt.Sprintf("Execute SQL fixture %s", path), Phase: PhaseSQLFixtures, File: path})
+		start := time.Now()
+		script, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := execer.ExecContext(this.context(), string(script)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		this.logger.Log(LogEvent{Level: LevelDebug, Message: "SQL fixture executed", Phase: PhaseSQLFixtures, File: path, Duration: time.Since(start)})
+	}
+	return nil
+}
+
+// isSqlFixture reports whether the file is a .sql fixture, maybe compressed.
+func isSqlFixture(name string) bool {
+	return filepath.Ext(strings.TrimSuffix(name, gzipExt)) == sqlFixtureExt
+}
+
+// sqlFixtureFiles returns the paths of the .sql fixtures sorted by their names, the nested ones by their paths.
+func (this *Fixturer) sqlFixtureFiles() ([]string, error) {
+	var paths []string
+	switch {
+	case this.fixtureSource != nil, this.fixturesFS != nil, this.fixturesArchive != "":
+		return nil, nil
+	case this.fixturesGlob != "":
+		files, err := globFiles(this.fixturesGlob)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if isSqlFixture(file.path) {
+				paths = append(paths, file.path)
+			}
+		}
+	case this.recursiveFixtures:
+		err := filepath.Walk(this.fixturesPathYml, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isSqlFixture(info.Name()) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The walk yields the paths in the lexical order already.
+		return paths, nil
+	default:
+		files, err := ioutil.ReadDir(this.fixturesPathYml)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !file.IsDir() && isSqlFixture(file.Name()) {
+				paths = append(paths, filepath.Join(this.fixturesPathYml, file.Name()))
+			}
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return filepath.Base(paths[i]) < filepath.Base(paths[j])
+	})
+	return paths, nil
+}
@@ -0,0 +1,165 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// SQLiteMemoryDatabase is the dbName of the in-memory SQLite database. It is shared by the connections of
+// the fixturer and lives while one of them is open, e.g. from LoadDbSchema until Close.
+const SQLiteMemoryDatabase = ":memory:"
+
+// SQLiteDialect is the dialect of SQLite, dbConf is the directory of the database file named dbName.
+// RecreateDatabase removes the file, which is created again by the first connection.
+// The foreign key checks are deferred to the commit rather than switched off, since PRAGMA foreign_keys
+// has no effect inside a transaction.
+type SQLiteDialect struct {
+	// Driver is the database/sql driver name.
+	Driver string
+}
+
+func (this SQLiteDialect) DriverName() string {
+	return this.Driver
+}
+
+func (SQLiteDialect) path(dbConf, name string) string {
+	return filepath.Join(dbConf, name)
+}
+
+func (this SQLiteDialect) DSN(dbConf, dbName, params string) (string, error) {
+	params = strings.Trim(params, "&?")
+	switch dbName {
+	case "":
+		// There is no server, the server connection is never used for the statements.
+		dbName, params = SQLiteMemoryDatabase, ""
+	case SQLiteMemoryDatabase:
+		params = joinDSNParams("mode=memory&cache=shared", params)
+	default:
+		dbName = this.path(dbConf, dbName)
+	}
+	if params == "" {
+		return "file:" + dbName, nil
+	}
+	return "file:" + dbName + "?" + params, nil
+}
+
+func (SQLiteDialect) QuoteIdentifier(name string) string {
+	return QuotePostgresIdentifier(name)
+}
+
+func (SQLiteDialect) PlaceholderFormat() squirrel.PlaceholderFormat {
+	return squirrel.Question
+}
+
+func (this SQLiteDialect) DropDatabase(ctx context.Context, server *sql.DB, dbConf, name string) error {
+	if name == SQLiteMemoryDatabase {
+		return nil
+	}
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if err := os.Remove(this.path(dbConf, name) + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this SQLiteDialect) CreateDatabase(ctx context.Context, server *sql.DB, dbConf, name string) error {
+	if name == SQLiteMemoryDatabase {
+		return nil
+	}
+	file, err := os.OpenFile(this.path(dbConf, name), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func (this SQLiteDialect) DatabaseExists(server *sql.DB, dbConf, name string) (bool, error) {
+	if name == SQLiteMemoryDatabase {
+		return false, nil
+	}
+	_, err := os.Stat(this.path(dbConf, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (SQLiteDialect) ForeignKeyChecks(enabled bool) string {
+	if enabled {
+		return "PRAGMA defer_foreign_keys = OFF"
+	}
+	return "PRAGMA defer_foreign_keys = ON"
+}
+
+func (SQLiteDialect) Truncate(quotedTable string) string {
+	return "DELETE FROM " + quotedTable
+}
+
+// StatementTimeout sets the time a statement waits for the lock of another connection.
+func (SQLiteDialect) StatementTimeout(timeout time.Duration) []string {
+	return []string{fmt.Sprintf("PRAGMA busy_timeout = %d", timeout.Milliseconds())}
+}
+
+func (SQLiteDialect) IgnoreDuplicates(qb squirrel.InsertBuilder) squirrel.InsertBuilder {
+	return qb.Options("OR IGNORE")
+}
+
+func (SQLiteDialect) Upsert(qb squirrel.InsertBuilder, quotedKeyColumns, quotedUpdateColumns []string) squirrel.InsertBuilder {
+	return excludedUpsert(qb, quotedKeyColumns, quotedUpdateColumns)
+}
+
+func (SQLiteDialect) LocalInfile() bool {
+	return false
+}
+
+func (SQLiteDialect) SingleWriter() bool {
+	return true
+}
+
+func (SQLiteDialect) TablesQuery() string {
+	return "SELECT name FROM sqlite_master WHERE type = 'table'"
+}
+
+// TableExistsQuery needs SQLite 3.37 for pragma_table_list.
+func (SQLiteDialect) TableExistsQuery() string {
+	return "SELECT COUNT(*) FROM pragma_table_list WHERE schema = ? AND name = ?"
+}
+
+func (SQLiteDialect) ColumnsQuery(qualified bool) string {
+	if qualified {
+		return "SELECT name FROM pragma_table_info(?2, ?1)"
+	}
+	return "SELECT name FROM pragma_table_info(?)"
+}
+
+func (SQLiteDialect) ForeignKeysQuery() string {
+	return `SELECT m.name, f."table" FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f WHERE m.type = 'table'`
+}
+
+func (SQLiteDialect) SelfReferencesQuery() string {
+	return `SELECT m.name, f."from", f."to" FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table' AND f."table" = m.name`
+}
+
+func (SQLiteDialect) PrimaryKeysQuery() string {
+	return `SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND p.pk > 0 ORDER BY m.name, p.pk`
+}
+
+func (SQLiteDialect) GeneratedColumnsQuery() string {
+	return `SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_xinfo(m.name) p
+		WHERE m.type = 'table' AND p.hidden IN (2, 3)`
+}
+
+func (SQLiteDialect) NumericColumnsQuery() string {
+	return `SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND (upper(p.type) LIKE 'DECIMAL%' OR upper(p.type) LIKE 'NUMERIC%' OR upper(p.type) = 'BIGINT')`
+}
@@ -0,0 +1,162 @@
+package fixturer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// checkStreamable fails if the fixture of the table can't be streamed, since the options need the whole file.
+func (this *Fixturer) checkStreamable(table string) error {
+	if this.templates {
+		return fmt.Errorf("fixture of %s is streamed, the streamed fixtures can't be templates (see WithTemplates)", table)
+	}
+	if len(this.deferredColumns[table]) > 0 {
+		return fmt.Errorf("fixture of %s is streamed, the streamed fixtures can't have deferred columns", table)
+	}
+	return nil
+}
+
+// streamInsert decodes the fixture file document by document and inserts its rows in batches,
+// so only a single batch of rows is kept in memory. The rows are repeated (see RepeatKey) and deduped
+// by the primary key row by row, the file must not be a multi-table fixture. The invalid UTF-8 is rejected
+// by the decoder.
+func (this *insertWorker) streamInsert(f *Fixturer, query *insertQuery) error {
+	file, err := openFile(query.file)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	batchSize := f.batchSize(query.table)
+	if batchSize <= 0 {
+		batchSize = StreamingBatchSize
+	}
+
+	query.streamedRows = 0
+	batch := make([]map[string]interface{}, 0, batchSize)
+	bulkLoad := true
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		columns := rowsColumns(batch)
+		if bulkLoad && f.bulkLoads(query.table, columns, batch) {
+			err := this.bulkLoad(query.table, columns, batch)
+			if err == nil {
+				query.streamedRows += len(batch)
+				batch = batch[:0]
+				return nil
+			}
+			if !isLocalInfileDisabled(err) {
+				return err
+			}
+			f.logger.Log(LogEvent{
+				Level:   LevelWarn,
+				Message: fmt.Sprintf("LOAD DATA LOCAL INFILE is not permitted, fall back to INSERT for %s. Origin error: %v", query.table, err),
+				Phase:   PhaseInserting,
+				Table:   query.table,
+				File:    query.file,
+				Err:     err,
+			})
+			bulkLoad = false
+		}
+		err := this.withIdentityInsert(f, query.table, columns, func() error {
+			for _, qb := range f.buildInserts(query.table, columns, batch) {
+				queryString, queryValues, err := qb.ToSql()
+				if err != nil {
+					return err
+				}
+				if _, err := this.tx.Exec(queryString, queryValues...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		query.streamedRows += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	var deduper *rowsDeduper
+	if f.dedupeByPrimaryKey {
+		primaryKeys, err := f.primaryKeys()
+		if err != nil {
+			return err
+		}
+		deduper = f.newRowsDeduper(query.table, primaryKeys)
+		defer deduper.logDropped(f, query)
+	}
+
+	fixture := &fixtureDecoder{path: query.file, strict: f.strictYAML, table: query.table, numericText: f.numericTextColumns}
+	reader := bufio.NewReader(file)
+	if bom, _ := reader.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+		reader.Discard(len(utf8BOM))
+	}
+	bom, _ := reader.Peek(2)
+	if err := checkNotUTF16(query.file, bom); err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(reader)
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if multiTableRoot(&document) != nil {
+			return fmt.Errorf("line %d: multi-table fixtures can't be streamed", document.Content[0].Line)
+		}
+		rows, err := fixture.nodeRows(&document)
+		if err == nil {
+			rows, err = f.repeatRows(query.file, rows)
+		}
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if f.maxRowsPerTable > 0 && query.streamedRows+len(batch) >= f.maxRowsPerTable {
+				return flush()
+			}
+			if err = f.expandEnv(row); err == nil {
+				err = f.resolveReferences(row)
+			}
+			if err == nil {
+				row, err = f.transformRow(query.table, row)
+			}
+			if err != nil {
+				return fmt.Errorf("row %d: %w", query.streamedRows+len(batch), err)
+			}
+			f.stripIgnoredColumns(query.table, row)
+			f.applyDefaults(row)
+			if err := encodeJSONValues(row); err != nil {
+				return fmt.Errorf("row %d: %w", query.streamedRows+len(batch), err)
+			}
+			if deduper != nil {
+				keep, err := deduper.keep(query.streamedRows+len(batch), row)
+				if err != nil {
+					return err
+				}
+				if !keep {
+					continue
+				}
+			}
+			batch = append(batch, row)
+			if len(batch) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return flush()
+}
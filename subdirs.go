@@ -0,0 +1,59 @@
+package fixturer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithRecursiveFixtures makes the fixtures directory walked with its subdirectories instead of the top level only.
+// The table of a nested fixture is named after the file as well, unless WithSubdirectorySchemas is set.
+func (this *Fixturer) WithRecursiveFixtures(enabled bool) IFixturer {
+	this.recursiveFixtures = enabled
+	this.dropCaches()
+	return this
+}
+
+// WithSubdirectorySchemas makes the subdirectory of a fixture found by WithRecursiveFixtures qualify its table,
+// e.g. billing/users.yml is loaded into billing.users, as are the unqualified tables of a multi-table fixture there.
+// The fixtures nested deeper than a single subdirectory fail the import then.
+func (this *Fixturer) WithSubdirectorySchemas(enabled bool) IFixturer {
+	this.subdirectorySchemas = enabled
+	this.dropCaches()
+	return this
+}
+
+// walkFixtureFiles returns the fixture files of the directory and its subdirectories.
+func (this *Fixturer) walkFixtureFiles(root string) ([]fixtureFile, error) {
+	var resultSlice []fixtureFile
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || fixtureExt(info.Name()) == "" {
+			return nil
+		}
+		dir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if dir == "." && info.Name() == ManifestFileName {
+			return nil
+		}
+
+		file := fixtureFile{FileInfo: info, path: path}
+		if this.subdirectorySchemas && dir != "." {
+			if strings.ContainsRune(dir, filepath.Separator) {
+				return fmt.Errorf("fixture %s is nested too deep for the subdirectory to be its schema", path)
+			}
+			file.schema = dir
+		}
+		resultSlice = append(resultSlice, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultSlice, nil
+}
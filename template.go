@@ -0,0 +1,130 @@
+package fixturer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"text/template"
+	"time"
+)
+
+// TemplateTimeLayout is the layout of the `now` template helper without an explicit one, the DATETIME literal.
+const TemplateTimeLayout = "2006-01-02 15:04:05"
+
+// WithTemplates makes the fixture files executed as text/template before they are decoded, so the values
+// may be computed, e.g. `created_at: "{{ now }}"` or `token: "{{ uuid }}"`. The helpers are:
+//
+//	now              the current UTC time as TemplateTimeLayout, or `now "2006-01-02"` with the layout
+//	uuid             a random UUID version 4
+//	env "NAME"       the environment variable, or `env "NAME" "default"` if it is not set
+//	randInt 1 100    a random integer of [1, 100)
+//	sha1 "text"      the hex SHA-1 of the text
+//	seq 1000         the numbers from 1 to 1000 to range over, e.g. to generate the rows
+//	fake.Email       a realistic random value, see Faker
+//
+// More helpers may be added with WithTemplateFuncs. A streamed file fails the import (see WithStreaming).
+// The .Index and .Number of the RepeatKey rows are left for the repeat, so both features may be combined.
+func (this *Fixturer) WithTemplates(enabled bool) IFixturer {
+	this.templates = enabled
+	this.dropCaches()
+	return this
+}
+
+// WithTemplateFuncs adds the helpers of the fixture templates (see WithTemplates), replacing the ones
+// of the same names.
+func (this *Fixturer) WithTemplateFuncs(funcs template.FuncMap) IFixturer {
+	if this.templateFuncs == nil {
+		this.templateFuncs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		this.templateFuncs[name] = fn
+	}
+	this.dropCaches()
+	return this
+}
+
+// fileTemplateData is the data of the fixture file templates, which renders the fields of repeatData
+// back as themselves, so they are executed for every repeated row.
+var fileTemplateData = struct {
+	Index  string
+	Number string
+}{"{{ .Index }}", "{{ .Number }}"}
+
+// executeTemplate executes the fixture content as a template if the templates are enabled.
+func (this *Fixturer) executeTemplate(path string, data []byte) ([]byte, error) {
+	if !this.templates {
+		return data, nil
+	}
+	tmpl, err := template.New(path).Option("missingkey=error").
+		Funcs(templateFuncs(this.newFaker(path))).Funcs(this.templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var result bytes.Buffer
+	if err := tmpl.Execute(&result, fileTemplateData); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
+
+// templateFuncs returns the built-in helpers of the fixture templates.
+func templateFuncs(faker *Faker) template.FuncMap {
+	return template.FuncMap{
+		"fake": func() *Faker {
+			return faker
+		},
+		"seq": func(n int) []int {
+			if n < 0 {
+				n = 0
+			}
+			seq := make([]int, n)
+			for i := range seq {
+				seq[i] = i + 1
+			}
+			return seq
+		},
+		"now": func(layout ...string) (string, error) {
+			switch len(layout) {
+			case 0:
+				return time.Now().UTC().Format(TemplateTimeLayout), nil
+			case 1:
+				return time.Now().UTC().Format(layout[0]), nil
+			}
+			return "", fmt.Errorf("now takes an optional layout, got %d arguments", len(layout))
+		},
+		"uuid": func() (string, error) {
+			var uuid [16]byte
+			if _, err := rand.Read(uuid[:]); err != nil {
+				return "", err
+			}
+			uuid[6] = uuid[6]&0x0f | 0x40
+			uuid[8] = uuid[8]&0x3f | 0x80
+			text := hex.EncodeToString(uuid[:])
+			return text[:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:], nil
+		},
+		"env": func(name string, defaultValue ...string) string {
+			if value, find := os.LookupEnv(name); find || len(defaultValue) == 0 {
+				return value
+			}
+			return defaultValue[0]
+		},
+		"randInt": func(min, max int) (int, error) {
+			if max <= min {
+				return 0, fmt.Errorf("randInt %d %d: max must be greater than min", min, max)
+			}
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min)))
+			if err != nil {
+				return 0, err
+			}
+			return min + int(n.Int64()), nil
+		},
+		"sha1": func(text string) string {
+			sum := sha1.Sum([]byte(text))
+			return hex.EncodeToString(sum[:])
+		},
+	}
+}
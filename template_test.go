@@ -0,0 +1,39 @@
+package fixturer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplates(t *testing.T) {
+	t.Setenv("FIXTURER_TEST_DOMAIN", "example.com")
+	dir := writeFixtures(t, map[string]string{
+		"users.yml": "{{ range $i := list 1 2 }}\n- id: {{ $i }}\n  email: \"user{{ $i }}@{{ env \"FIXTURER_TEST_DOMAIN\" }}\"\n" +
+			"  token: \"{{ sha1 \"secret\" }}\"\n  role: \"{{ env \"FIXTURER_TEST_UNSET\" \"guest\" }}\"\n{{ end }}",
+	})
+	f := NewFixturer("", "", dir, "test", "").WithLogger(LoggerFunc(func(LogEvent) {})).WithTemplates(true).
+		WithTemplateFuncs(template.FuncMap{"list": func(values ...int) []int { return values }})
+
+	query, args, err := f.BuildSQL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[map[email:user1@example.com id:1 role:guest token:e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4] " +
+		"map[email:user2@example.com id:2 role:guest token:e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4]]"
+	if got := fmt.Sprint(insertedRows(query, args)); got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+
+	f.WithTemplates(false)
+	if _, _, err := f.BuildSQL("users"); err == nil {
+		t.Error("got no error for the template without WithTemplates")
+	}
+
+	dir = writeFixtures(t, map[string]string{"orders.yml": "- id: {{ .ID }}\n"})
+	f = NewFixturer("", "", dir, "test", "").WithTemplates(true)
+	if _, _, err := f.BuildSQL("orders"); err == nil || !strings.Contains(err.Error(), "ID") {
+		t.Errorf("got error %v, want the undefined field ID", err)
+	}
+}
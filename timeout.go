@@ -0,0 +1,80 @@
+package fixturer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned when the operation does not complete within the timeout set by WithTimeout.
+// It wraps context.DeadlineExceeded.
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+}
+
+func (this *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", this.Operation, this.Timeout)
+}
+
+func (this *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// WithTimeout bounds the total time of ImportFixtures, LoadDbSchema, RecreateDatabase, DropDatabase and Ping,
+// as well as of their Context variants, zero means no timeout.
+// On expiry the in-flight statements and transactions are cancelled and *TimeoutError is returned.
+// RecreateDatabaseWithSchemaAndImportFixtures gets the single timeout for all of its steps.
+func (this *Fixturer) WithTimeout(timeout time.Duration) IFixturer {
+	this.timeout = timeout
+	return this
+}
+
+// withContext runs the operation with the context bounded by the timeout, if any, unless it runs within
+// another operation. The error of the cancelled operation wraps the error of the context.
+func (this *Fixturer) withContext(parent context.Context, operation string, run func() error) error {
+	if this.ctx != nil {
+		return run()
+	}
+
+	ctx, cancel := parent, context.CancelFunc(func() {})
+	if this.timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, this.timeout)
+	}
+	this.ctx = ctx
+	defer func() {
+		cancel()
+		this.ctx = nil
+	}()
+
+	err := run()
+	switch {
+	case err == nil || ctx.Err() == nil:
+		return err
+	case parent.Err() == nil:
+		return &TimeoutError{Operation: operation, Timeout: this.timeout}
+	case errors.Is(err, parent.Err()):
+		return err
+	}
+	return fmt.Errorf("%s: %w", operation, parent.Err())
+}
+
+// context returns the context of the running operation.
+func (this *Fixturer) context() context.Context {
+	if this.ctx == nil {
+		return context.Background()
+	}
+	return this.ctx
+}
+
+// ctxTx runs the statements of the worker transaction with the context it was started with.
+type ctxTx struct {
+	*sql.Tx
+	ctx context.Context
+}
+
+func (this ctxTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return this.Tx.ExecContext(this.ctx, query, args...)
+}
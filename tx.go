@@ -0,0 +1,78 @@
+package fixturer
+
+import "database/sql"
+
+// ownedTx is the transaction of the caller, the fixturer never commits nor rolls it back.
+type ownedTx struct {
+	*sql.Tx
+}
+
+func (ownedTx) Commit() error {
+	return nil
+}
+
+func (ownedTx) Rollback() error {
+	return nil
+}
+
+// ImportFixturesTx imports the fixtures into the transaction of the caller without committing or closing anything,
+// e.g. for a test rolling its transaction back at the end. The tables are cleared with DELETE, since TRUNCATE
+// would commit the transaction implicitly. The foreign key checks, if disabled, are restored in the transaction
+// after the import. The .sql fixtures are executed in the transaction as well. The existence of the fixture tables
+// is not checked. The fixtures are parsed with the introspection queries run in the transaction.
+func (this *Fixturer) ImportFixturesTx(tx *sql.Tx) error {
+	files, err := this.fixtureFiles()
+	if err != nil {
+		return err
+	}
+	this.introspectionTx = tx
+	defer func() { this.introspectionTx = nil }()
+	if err := this.parseFixtures(files); err != nil {
+		return err
+	}
+	this.lastImportTables, this.lastImportRows, this.lastEmptyTables = nil, 0, nil
+
+	tables, _, err := this.tablesOrder(tx)
+	if err != nil {
+		return err
+	}
+
+	if this.disableForeignKeys {
+		if _, err := tx.Exec(this.dialect.ForeignKeyChecks(false)); err != nil {
+			return err
+		}
+		defer tx.Exec(this.dialect.ForeignKeyChecks(true))
+	}
+
+	this.progress.start(PhaseTruncating, len(tables))
+	for i := len(tables) - 1; i >= 0; i-- {
+		this.progress.step(tables[i], 0)
+		if this.skipsClear(tables[i]) {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM " + this.quoteTable(tables[i])); err != nil {
+			return &FixtureError{Phase: PhaseTruncating, Table: tables[i], Err: err}
+		}
+	}
+
+	queries := this.queriesInOrder(tables, false)
+	worker := &insertWorker{tx: ownedTx{tx}}
+	rowsCnt := 0
+	this.progress.start(PhaseInserting, len(queries))
+	for _, query := range queries {
+		if err := worker.insert(this, query); err != nil {
+			return queryError(PhaseInserting, query, err)
+		}
+		this.progress.step(query.table, query.rowsCount())
+		rowsCnt += query.rowsCount()
+	}
+	if err := worker.updateDeferred(this, queries); err != nil {
+		return err
+	}
+	if err := this.execSqlFixtures(tx); err != nil {
+		return err
+	}
+
+	this.setLastImport(this.cache().tables, rowsCnt)
+	return nil
+}
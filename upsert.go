@@ -0,0 +1,71 @@
+package fixturer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// UpsertDialect is a Dialect able to update the existing rows of the inserted keys (see WithUpsert).
+type UpsertDialect interface {
+	Dialect
+	// Upsert makes the insert update the columns of the rows conflicting on the key columns.
+	// The update columns may be empty, the conflicting rows are kept as they are then.
+	Upsert(qb squirrel.InsertBuilder, quotedKeyColumns, quotedUpdateColumns []string) squirrel.InsertBuilder
+}
+
+// WithUpsert makes the inserts update the existing rows of the same keys with the fixture values,
+// e.g. INSERT ... ON DUPLICATE KEY UPDATE of MySQL. The tables are not cleared in this mode, so the fixtures
+// are applied on top of the existing rows. The dialects naming the conflict target use the key columns
+// of the table (see SetKeyColumns).
+func (this *Fixturer) WithUpsert(enabled bool) IFixturer {
+	this.upsert = enabled
+	this.dropCaches()
+	return this
+}
+
+// upserts reports whether the inserts of the table update the existing rows.
+func (this *Fixturer) upserts(table string) bool {
+	return this.upsert || this.tableOptions(table).upsert
+}
+
+// checkUpsert fails if a table is upserted while the dialect can't do it.
+func (this *Fixturer) checkUpsert(queries []*insertQuery) error {
+	if _, ok := this.dialect.(UpsertDialect); ok {
+		return nil
+	}
+	for _, query := range queries {
+		if this.upserts(query.table) {
+			return fmt.Errorf("dialect %T does not support upserts of table %s", this.dialect, query.table)
+		}
+	}
+	return nil
+}
+
+// upsertColumns returns the quoted key columns of the table and the other quoted columns, which are updated.
+func (this *Fixturer) upsertColumns(table string, columns []string) (keyColumns []string, updateColumns []string) {
+	keys := map[string]struct{}{}
+	for _, column := range this.tableKeyColumns(table) {
+		keys[column] = struct{}{}
+		keyColumns = append(keyColumns, this.quoteIdentifier(column))
+	}
+	for _, column := range columns {
+		if _, find := keys[column]; !find {
+			updateColumns = append(updateColumns, this.quoteIdentifier(column))
+		}
+	}
+	return keyColumns, updateColumns
+}
+
+// excludedUpsert is the ON CONFLICT clause of PostgreSQL and SQLite.
+func excludedUpsert(qb squirrel.InsertBuilder, quotedKeyColumns, quotedUpdateColumns []string) squirrel.InsertBuilder {
+	if len(quotedUpdateColumns) == 0 {
+		return qb.Suffix("ON CONFLICT DO NOTHING")
+	}
+	set := make([]string, len(quotedUpdateColumns))
+	for i, column := range quotedUpdateColumns {
+		set[i] = column + " = EXCLUDED." + column
+	}
+	return qb.Suffix("ON CONFLICT (" + strings.Join(quotedKeyColumns, ", ") + ") DO UPDATE SET " + strings.Join(set, ", "))
+}
@@ -0,0 +1,40 @@
+package fixturer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpsert(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{"users.yml": "- id: 1\n  name: Alice\n"})
+	for _, test := range []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"mysql", MySQLDialect{}, " ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)"},
+		{"postgres", PostgresDialect{}, ` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewFixturer("", "", dir, "test", "").WithDialect(test.dialect).WithUpsert(true)
+			query, _, err := f.BuildSQL("users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(query, test.want) {
+				t.Errorf("got query %s, want the suffix %s", query, test.want)
+			}
+		})
+	}
+
+	db, fake := openFakeDB(t, respondTables("users"))
+	if err := newFakeFixturer(db, dir).WithUpsert(true).ImportFixtures(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if cleared := append(fake.executed("TRUNCATE"), fake.executed("DELETE")...); len(cleared) > 0 {
+		t.Errorf("got the tables cleared by %s", cleared[0].query)
+	}
+	if inserts := fake.executed("INSERT INTO `users`"); len(inserts) != 1 || !strings.Contains(inserts[0].query, "ON DUPLICATE KEY UPDATE") {
+		t.Errorf("got inserts %v, want one upsert", inserts)
+	}
+}
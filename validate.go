@@ -0,0 +1,51 @@
+package fixturer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError is returned by Validate listing the errors of every invalid fixture.
+type ValidationError struct {
+	Errors []*FixtureError
+}
+
+func (this *ValidationError) Error() string {
+	messages := make([]string, len(this.Errors))
+	for i, err := range this.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d fixture errors: %s", len(this.Errors), strings.Join(messages, "; "))
+}
+
+// Validate parses every fixture file without connecting to the database, e.g. in a pre-commit hook.
+// A fixture must be well-formed as in the strict mode (see WithStrictYAML). The rows of a table may omit
+// some columns (see WithMissingColumns). All the invalid fixtures are reported at once with *ValidationError.
+func (this *Fixturer) Validate() error {
+	files, err := this.fixtureFiles()
+	if err != nil {
+		return err
+	}
+
+	result := &ValidationError{}
+	for _, f := range files {
+		data, err := fixtureData(f)
+		if err == nil {
+			data, err = fixtureText(f.path, data)
+		}
+		if err == nil {
+			data, err = this.executeTemplate(f.path, data)
+		}
+		if err == nil {
+			_, err = (&fixtureDecoder{path: f.path, strict: true, table: f.tableName()}).decodeTables(data)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, &FixtureError{Phase: PhaseParsing, Table: f.tableName(), File: f.path, Err: err})
+		}
+	}
+
+	if len(result.Errors) == 0 {
+		return nil
+	}
+	return result
+}
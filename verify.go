@@ -0,0 +1,45 @@
+package fixturer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// ValidateAgainstSchema checks the fixtures against the schema without touching the database: it creates
+// a uniquely named temporary database, loads the schema, imports the fixtures with the strict YAML parsing
+// and drops the temporary database whatever the outcome. The fixtures are parsed again by the next import.
+func (this *Fixturer) ValidateAgainstSchema() (err error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	dbName, db, strict, keep := this.dbName, this.db, this.strictYAML, this.keepOnFailure
+	this.dbName, this.db, this.strictYAML, this.keepOnFailure = "fixturer_verify_"+hex.EncodeToString(suffix), nil, true, false
+	this.dropCache()
+	defer func() {
+		if dropErr := this.DropDatabase(); dropErr != nil && err == nil {
+			err = fmt.Errorf("drop temporary database %s: %w", this.dbName, dropErr)
+		}
+		this.dbName, this.db, this.strictYAML, this.keepOnFailure = dbName, db, strict, keep
+		this.dropCache()
+	}()
+
+	return this.withContext(context.Background(), "validate fixtures against schema", func() error {
+		server, err := this.openServerDb()
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+
+		if err := this.recreateDatabaseOn(server); err != nil {
+			return err
+		}
+		if err := this.loadDbSchema(); err != nil {
+			return err
+		}
+		return this.importFixtures()
+	})
+}